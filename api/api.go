@@ -1,12 +1,12 @@
 package api
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"encoding/xml"
-	"log"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -64,21 +64,152 @@ type Field struct {
 	FieldHelp        string `json:"fieldHelp"`
 	Audited          bool   `json:"audited"`
 	Properties       struct {
-		PrimaryKey      bool   `json:"primaryKey"`
-		ForeignKey      bool   `json:"foreignKey"`
-		NumLines        int    `json:"numLines"`
-		MaxLength       int    `json:"maxLength"`
-		AppendOnly      bool   `json:"appendOnly"`
-		AllowHTML       bool   `json:"allowHTML"`
-		AllowMentions   bool   `json:"allowMentions"`
-		SortAsGiven     bool   `json:"sortAsGiven"`
-		CarryChoices    bool   `json:"carryChoices"`
-		AllowNewChoices bool   `json:"allowNewChoices"`
-		Formula         string `json:"formula"`
-		DefaultValue    string `json:"defaultValue"`
+		PrimaryKey              bool     `json:"primaryKey"`
+		ForeignKey              bool     `json:"foreignKey"`
+		NumLines                int      `json:"numLines"`
+		MaxLength               int      `json:"maxLength"`
+		AppendOnly              bool     `json:"appendOnly"`
+		AllowHTML               bool     `json:"allowHTML"`
+		AllowMentions           bool     `json:"allowMentions"`
+		SortAsGiven             bool     `json:"sortAsGiven"`
+		CarryChoices            bool     `json:"carryChoices"`
+		AllowNewChoices         bool     `json:"allowNewChoices"`
+		Formula                 string   `json:"formula"`
+		DefaultValue            string   `json:"defaultValue"`
+		LookupTargetFieldID     int      `json:"lookupTargetFieldId,omitempty"`
+		LookupReferenceFieldID  int      `json:"lookupReferenceFieldId,omitempty"`
+		SummaryFunction         string   `json:"summaryFunction,omitempty"`
+		SummaryTargetFieldID    int      `json:"summaryTargetFieldId,omitempty"`
+		SummaryReferenceFieldID int      `json:"summaryReferenceFieldId,omitempty"`
+		Choices                 []string `json:"choices,omitempty"`
+		CompositeFields         []int    `json:"compositeFields,omitempty"`
 	} `json:"properties"`
 }
 
+type RelationshipField struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+	Type  string `json:"type"`
+}
+
+type Relationship struct {
+	ID              int                 `json:"id"`
+	ParentTableID   string              `json:"parentTableId"`
+	ChildTableID    string              `json:"childTableId"`
+	ForeignKeyField RelationshipField   `json:"foreignKeyField"`
+	IsCrossApp      bool                `json:"isCrossApp"`
+	LookupFields    []RelationshipField `json:"lookupFields"`
+	SummaryFields   []RelationshipField `json:"summaryFields"`
+}
+
+type GetRelationshipsResponse struct {
+	Relationships []Relationship `json:"relationships"`
+	Metadata      struct {
+		NumRelationships   int `json:"numRelationships"`
+		Skip               int `json:"skip"`
+		TotalRelationships int `json:"totalRelationships"`
+	} `json:"metadata"`
+}
+
+type RelationshipSummaryField struct {
+	SummaryFid       int    `json:"summaryFid,omitempty"`
+	Label            string `json:"label"`
+	AccumulationType string `json:"accumulationType"`
+	Where            string `json:"where,omitempty"`
+}
+
+type CreateRelationshipBody struct {
+	ParentTableID   string `json:"parentTableId"`
+	ForeignKeyField struct {
+		Label string `json:"label"`
+	} `json:"foreignKeyField"`
+	LookupFieldIDs []int                      `json:"lookupFieldIds,omitempty"`
+	SummaryFields  []RelationshipSummaryField `json:"summaryFields,omitempty"`
+}
+
+type CreateRelationshipResponse struct {
+	Relationship
+}
+
+// Record holds the value of each field keyed by field ID
+type Record map[string]RecordValue
+
+type RecordValue struct {
+	Value any `json:"value"`
+}
+
+type QueryOptions struct {
+	Skip int `json:"skip"`
+	Top  int `json:"top,omitempty"`
+}
+
+type QueryRecordsBody struct {
+	From    string       `json:"from"`
+	Select  []int        `json:"select"`
+	Options QueryOptions `json:"options"`
+}
+
+type QueryRecordsResponse struct {
+	Data     []Record `json:"data"`
+	Metadata struct {
+		NumRecords   int `json:"numRecords"`
+		Skip         int `json:"skip"`
+		TotalRecords int `json:"totalRecords"`
+	} `json:"metadata"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+type UpsertRecordsBody struct {
+	To           string   `json:"to"`
+	Data         []Record `json:"data"`
+	MergeFieldID int      `json:"mergeFieldId,omitempty"`
+}
+
+type UpsertRecordsResponse struct {
+	Metadata struct {
+		CreatedRecordIDs              []int               `json:"createdRecordIds"`
+		UpdatedRecordIDs              []int               `json:"updatedRecordIds"`
+		UnchangedRecordIDs            []int               `json:"unchangedRecordIds"`
+		LineErrors                    map[string][]string `json:"lineErrors"`
+		TotalNumberOfRecordsProcessed int                 `json:"totalNumberOfRecordsProcessed"`
+	} `json:"metadata"`
+}
+
+type CreateTableBody struct {
+	Name             string `json:"name"`
+	Description      string `json:"description,omitempty"`
+	SingleRecordName string `json:"singleRecordName,omitempty"`
+	PluralRecordName string `json:"pluralRecordName,omitempty"`
+}
+
+type CreateTableResponse struct {
+	Table
+}
+
+type CreateFieldBody struct {
+	Label            string         `json:"label"`
+	FieldType        string         `json:"fieldType"`
+	FieldHelp        string         `json:"fieldHelp,omitempty"`
+	NoWrap           bool           `json:"noWrap"`
+	Bold             bool           `json:"bold"`
+	AppearsByDefault bool           `json:"appearsByDefault"`
+	FindEnabled      bool           `json:"findEnabled"`
+	Audited          bool           `json:"audited"`
+	Properties       map[string]any `json:"properties,omitempty"`
+}
+
+type CreateFieldResponse struct {
+	Field
+}
+
+type Report struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
 type GetTablesResponse struct {
 	AppId  string
 	Tables []Table
@@ -92,17 +223,15 @@ type GetPageResponse struct {
 }
 
 type GetPageBody struct {
-	XMLName   xml.Name `xml:"qdbapi"`
-	UserToken string   `xml:"usertoken"`
-	PageID    string   `xml:"pageID"`
+	XMLName xml.Name `xml:"qdbapi"`
+	PageID  string   `xml:"pageID"`
 }
 
 type ReplacePageBody struct {
-	XMLName   xml.Name `xml:"qdbapi"`
-	UserToken string   `xml:"usertoken"`
-	PageType  string   `xml:"pagetype"`
-	PageID    string   `xml:"pageID"`
-	PageBody  string   `xml:"pagebody"`
+	XMLName  xml.Name `xml:"qdbapi"`
+	PageType string   `xml:"pagetype"`
+	PageID   string   `xml:"pageID"`
+	PageBody string   `xml:"pagebody"`
 }
 
 type ReplacePageResponse struct {
@@ -114,10 +243,9 @@ type ReplacePageResponse struct {
 }
 
 type UpdateFieldBody struct {
-	XMLName   xml.Name `xml:"qdbapi"`
-	FieldID   string   `xml:"fid"`
-	Formula   string   `xml:"formula"`
-	UserToken string   `xml:"usertoken"`
+	XMLName xml.Name `xml:"qdbapi"`
+	FieldID string   `xml:"fid"`
+	Formula string   `xml:"formula"`
 }
 
 type UpdateFieldResponse struct {
@@ -128,230 +256,173 @@ type UpdateFieldResponse struct {
 	ErrorText string   `xml:"errtext"`
 }
 
-type Quickbase struct {
-	AppId     string
-	UserToken string
-	Realm     string
+type GetSchemaBody struct {
+	XMLName xml.Name `xml:"qdbapi"`
 }
 
-func (q *Quickbase) GetApp() App {
-	client := http.Client{}
+type SchemaPage struct {
+	ID       string `xml:"id,attr"`
+	Type     string `xml:"type,attr"`
+	PageName string `xml:"name,attr"`
+	Text     string `xml:",chardata"`
+}
 
-	req, err := http.NewRequest("GET", "https://api.quickbase.com/v1/apps/"+q.AppId, nil)
+type SchemaVariable struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
 
-	if err != nil {
-		log.Fatal(err)
-	}
+type SchemaPermission struct {
+	Role           string `xml:"role"`
+	PermissionType string `xml:"permissionType"`
+}
 
-	req.Header = http.Header{
-		"QB-Realm-Hostname": {q.Realm},
-		"Authorization":     {"QB-USER-TOKEN " + q.UserToken},
-	}
+type SchemaField struct {
+	ID          string             `xml:"id,attr"`
+	FieldType   string             `xml:"field_type,attr"`
+	Label       string             `xml:"label"`
+	Permissions []SchemaPermission `xml:"permissions>permission"`
+}
 
-	res, err := client.Do(req)
+type GetSchemaResponse struct {
+	XMLName   xml.Name `xml:"qdbapi"`
+	ErrorCode string   `xml:"errcode"`
+	ErrorText string   `xml:"errtext"`
+	Table     struct {
+		Name        string             `xml:"name"`
+		Variables   []SchemaVariable   `xml:"variables>var"`
+		Pages       []SchemaPage       `xml:"pages>page"`
+		Permissions []SchemaPermission `xml:"permissions>permission"`
+		Fields      []SchemaField      `xml:"fields>field"`
+	} `xml:"table"`
+}
 
-	if err != nil {
-		log.Fatal(err)
-	}
+type GetRoleInfoBody struct {
+	XMLName xml.Name `xml:"qdbapi"`
+}
 
-	var app App
+type Role struct {
+	ID     string `xml:"id,attr"`
+	Name   string `xml:"name"`
+	Access string `xml:"access"`
+}
+
+type GetRoleInfoResponse struct {
+	XMLName   xml.Name `xml:"qdbapi"`
+	ErrorCode string   `xml:"errcode"`
+	ErrorText string   `xml:"errtext"`
+	Roles     []Role   `xml:"roles>role"`
+}
 
-	json.NewDecoder(res.Body).Decode(&app)
+type GetDBVarBody struct {
+	XMLName xml.Name `xml:"qdbapi"`
+	VarName string   `xml:"varname"`
+}
 
-	return app
+type GetDBVarResponse struct {
+	XMLName   xml.Name `xml:"qdbapi"`
+	ErrorCode string   `xml:"errcode"`
+	ErrorText string   `xml:"errtext"`
+	Value     string   `xml:"value"`
 }
 
-func (q *Quickbase) GetTables() GetTablesResponse {
-	client := http.Client{}
+type SetDBVarBody struct {
+	XMLName xml.Name `xml:"qdbapi"`
+	VarName string   `xml:"varname"`
+	Value   string   `xml:"value"`
+}
 
-	req, err := http.NewRequest("GET", "https://api.quickbase.com/v1/tables?appId="+q.AppId, nil)
+type SetDBVarResponse struct {
+	XMLName   xml.Name `xml:"qdbapi"`
+	Action    string   `xml:"action"`
+	ErrorCode string   `xml:"errcode"`
+	ErrorText string   `xml:"errtext"`
+}
 
-	if err != nil {
-		log.Fatal(err)
+// Name of the page, which is either an attribute or the content of the page element
+func (p SchemaPage) Name() string {
+	if p.PageName != "" {
+		return p.PageName
 	}
 
-	req.Header = http.Header{
-		"QB-Realm-Hostname": {q.Realm},
-		"Authorization":     {"QB-USER-TOKEN " + q.UserToken},
-	}
+	return strings.TrimSpace(p.Text)
+}
 
-	res, err := client.Do(req)
+// Records read per page of a record query
+const RECORDS_PAGE_SIZE = 1000
 
-	if err != nil {
-		log.Fatal(err)
-	}
+type Quickbase struct {
+	AppId     string
+	UserToken string
+	AppToken  string
+	Realm     string
+	Auth      Auth
 
-	var tables []Table
+	// Client sends the requests of the app, RequestTimeout bounds each of them
+	Client         *http.Client
+	RequestTimeout time.Duration
+}
+
+func (q *Quickbase) GetApp(ctx context.Context) (App, error) {
+	return CallREST[App](ctx, q, "GET", "/apps/"+q.AppId, nil)
+}
 
-	json.NewDecoder(res.Body).Decode(&tables)
+func (q *Quickbase) GetTables(ctx context.Context) (GetTablesResponse, error) {
+	tables, err := CallREST[[]Table](ctx, q, "GET", "/tables?appId="+q.AppId, nil)
 
 	return GetTablesResponse{
 		AppId:  q.AppId,
 		Tables: tables,
-	}
+	}, err
 }
 
-func (q *Quickbase) GetPage(pageId string) GetPageResponse {
-	client := http.Client{}
-
-	xmlBody, err := xml.MarshalIndent(GetPageBody{
-		UserToken: q.UserToken,
-		PageID:    pageId,
-	}, " ", "  ")
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	body := bytes.NewReader(xmlBody)
-
-	req, err := http.NewRequest("POST", "https://"+q.Realm+"/db/"+q.AppId, body)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	req.Header = http.Header{
-		"Content-Type":     {"application/xml"},
-		"QUICKBASE-ACTION": {"API_GetDBPage"},
-	}
-
-	res, err := client.Do(req)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	var pageResponse GetPageResponse
-
-	xml.NewDecoder(res.Body).Decode(&pageResponse)
-
-	if pageResponse.ErrorCode != "0" {
-		log.Fatal(pageResponse.ErrorText)
-	}
-
-	return pageResponse
+func (q *Quickbase) GetPage(ctx context.Context, pageId string) (GetPageResponse, error) {
+	return CallXML[GetPageBody, GetPageResponse](ctx, q, q.AppId, "API_GetDBPage", GetPageBody{PageID: pageId})
 }
 
-func (q *Quickbase) ReplacePage(pageId string, pageBody string) ReplacePageResponse {
-	client := http.Client{}
-
-	xmlBody, err := xml.MarshalIndent(ReplacePageBody{
-		UserToken: q.UserToken,
-		PageType:  "1",
-		PageID:    pageId,
-		PageBody:  pageBody,
-	}, " ", "  ")
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	body := bytes.NewReader(xmlBody)
-
-	req, err := http.NewRequest("POST", "https://"+q.Realm+"/db/"+q.AppId, body)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	req.Header = http.Header{
-		"Content-Type":     {"application/xml"},
-		"QUICKBASE-ACTION": {"API_AddReplaceDBPage"},
-	}
-
-	res, err := client.Do(req)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	var response ReplacePageResponse
-
-	xml.NewDecoder(res.Body).Decode(&response)
-
-	if response.ErrorCode != "0" {
-		log.Fatal(response.ErrorText)
-	}
-
-	return response
+func (q *Quickbase) GetSchema(ctx context.Context, dbid string) (GetSchemaResponse, error) {
+	return CallXML[GetSchemaBody, GetSchemaResponse](ctx, q, dbid, "API_GetSchema", GetSchemaBody{})
 }
 
-func (q *Quickbase) GetFields(tableId string) []Field {
-	client := http.Client{}
-
-	req, err := http.NewRequest("GET", "https://api.quickbase.com/v1/fields?tableId="+tableId, nil)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	req.Header = http.Header{
-		"QB-Realm-Hostname": {q.Realm},
-		"Authorization":     {"QB-USER-TOKEN " + q.UserToken},
-	}
-
-	res, err := client.Do(req)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	var fields []Field
-
-	json.NewDecoder(res.Body).Decode(&fields)
-
-	return fields
+func (q *Quickbase) ReplacePage(ctx context.Context, pageId string, pageBody string) (ReplacePageResponse, error) {
+	return CallXML[ReplacePageBody, ReplacePageResponse](ctx, q, q.AppId, "API_AddReplaceDBPage", ReplacePageBody{
+		PageType: "1",
+		PageID:   pageId,
+		PageBody: pageBody,
+	})
 }
 
-func (q *Quickbase) UpdateField(tableId string, fieldId string, formula string) UpdateFieldResponse {
-	client := http.Client{}
-
-	xmlBody, err := xml.MarshalIndent(UpdateFieldBody{
-		UserToken: q.UserToken,
-		FieldID:   fieldId,
-		Formula:   formula,
-	}, " ", "  ")
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	body := bytes.NewReader(xmlBody)
-
-	req, err := http.NewRequest("POST", "https://"+q.Realm+"/db/"+tableId, body)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	req.Header = http.Header{
-		"Content-Type":     {"application/xml"},
-		"QUICKBASE-ACTION": {"API_SetFieldProperties"},
-	}
-
-	res, err := client.Do(req)
-
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	var response UpdateFieldResponse
+func (q *Quickbase) GetRoleInfo(ctx context.Context) (GetRoleInfoResponse, error) {
+	return CallXML[GetRoleInfoBody, GetRoleInfoResponse](ctx, q, q.AppId, "API_GetRoleInfo", GetRoleInfoBody{})
+}
 
-	xml.NewDecoder(res.Body).Decode(&response)
+func (q *Quickbase) GetDBVar(ctx context.Context, name string) (GetDBVarResponse, error) {
+	return CallXML[GetDBVarBody, GetDBVarResponse](ctx, q, q.AppId, "API_GetDBvar", GetDBVarBody{VarName: name})
+}
 
-	if response.ErrorCode != "0" {
-		log.Fatal(response.ErrorText)
-	}
+func (q *Quickbase) SetDBVar(ctx context.Context, name string, value string) (SetDBVarResponse, error) {
+	return CallXML[SetDBVarBody, SetDBVarResponse](ctx, q, q.AppId, "API_SetDBvar", SetDBVarBody{
+		VarName: name,
+		Value:   value,
+	})
+}
 
-	return response
+func (q *Quickbase) GetReports(ctx context.Context, tableId string) ([]Report, error) {
+	return CallREST[[]Report](ctx, q, "GET", "/reports?tableId="+tableId, nil)
 }
 
-func (q *Quickbase) UpdateFieldLength(tableId string, fieldId int, fieldType string) Field {
-	client := http.Client{}
+func (q *Quickbase) GetFields(ctx context.Context, tableId string) ([]Field, error) {
+	return CallREST[[]Field](ctx, q, "GET", "/fields?tableId="+tableId, nil)
+}
 
-	fieldIdStr := strconv.Itoa(fieldId)
+func (q *Quickbase) UpdateField(ctx context.Context, tableId string, fieldId string, formula string) (UpdateFieldResponse, error) {
+	return CallXML[UpdateFieldBody, UpdateFieldResponse](ctx, q, tableId, "API_SetFieldProperties", UpdateFieldBody{
+		FieldID: fieldId,
+		Formula: formula,
+	})
+}
 
+func (q *Quickbase) UpdateFieldLength(ctx context.Context, tableId string, fieldId int, fieldType string) (Field, error) {
 	var maxLength int
 
 	if fieldType == "text" {
@@ -359,35 +430,78 @@ func (q *Quickbase) UpdateFieldLength(tableId string, fieldId int, fieldType str
 	} else if fieldType == "text-multi-line" {
 		maxLength = 200
 	} else {
-		log.Fatalln("Invalid Field Type")
+		return Field{}, errors.New("invalid field type " + fieldType)
 	}
 
-	body, _ := json.Marshal(map[string]interface{}{
+	body := map[string]interface{}{
 		"properties": map[string]interface{}{
 			"maxLength": maxLength,
 		},
-	})
+	}
 
-	req, err := http.NewRequest("POST", "https://api.quickbase.com/v1/fields/"+fieldIdStr+"?tableId="+tableId, bytes.NewBuffer(body))
+	return CallREST[Field](ctx, q, "POST", "/fields/"+strconv.Itoa(fieldId)+"?tableId="+tableId, body)
+}
 
-	if err != nil {
-		log.Fatal(err)
-	}
+// Relationships in which the table is the child, fetched page by page
+func (q *Quickbase) GetRelationships(ctx context.Context, tableId string) ([]Relationship, error) {
+	relationships := make([]Relationship, 0)
+
+	for {
+		response, err := CallREST[GetRelationshipsResponse](ctx, q, "GET", "/tables/"+tableId+"/relationships?skip="+strconv.Itoa(len(relationships)), nil)
+
+		if err != nil {
+			return relationships, err
+		}
+
+		relationships = append(relationships, response.Relationships...)
 
-	req.Header = http.Header{
-		"QB-Realm-Hostname": {q.Realm},
-		"Authorization":     {"QB-USER-TOKEN " + q.UserToken},
+		if len(response.Relationships) == 0 || len(relationships) >= response.Metadata.TotalRelationships {
+			return relationships, nil
+		}
 	}
+}
+
+func (q *Quickbase) CreateRelationship(ctx context.Context, childTableId string, relationship CreateRelationshipBody) (CreateRelationshipResponse, error) {
+	return CallREST[CreateRelationshipResponse](ctx, q, "POST", "/tables/"+childTableId+"/relationship", relationship)
+}
+
+// Records of a table, following metadata.skip until every record has been read
+func (q *Quickbase) QueryRecords(ctx context.Context, tableId string, fieldIds []int) ([]Record, error) {
+	records := make([]Record, 0)
+	skip := 0
+
+	for {
+		response, err := CallREST[QueryRecordsResponse](ctx, q, "POST", "/records/query", QueryRecordsBody{
+			From:    tableId,
+			Select:  fieldIds,
+			Options: QueryOptions{Skip: skip, Top: RECORDS_PAGE_SIZE},
+		})
 
-	res, err := client.Do(req)
+		if err != nil {
+			return records, err
+		}
 
-	if err != nil {
-		log.Fatal(err)
+		records = append(records, response.Data...)
+		skip = response.Metadata.Skip + response.Metadata.NumRecords
+
+		if response.Metadata.NumRecords == 0 || skip >= response.Metadata.TotalRecords {
+			return records, nil
+		}
 	}
+}
 
-	var field Field
+func (q *Quickbase) UpsertRecords(ctx context.Context, tableId string, records []Record, mergeFieldId int) (UpsertRecordsResponse, error) {
+	return CallREST[UpsertRecordsResponse](ctx, q, "POST", "/records", UpsertRecordsBody{
+		To:           tableId,
+		Data:         records,
+		MergeFieldID: mergeFieldId,
+	})
+}
 
-	json.NewDecoder(res.Body).Decode(&field)
+func (q *Quickbase) CreateTable(ctx context.Context, table CreateTableBody) (CreateTableResponse, error) {
+	return CallREST[CreateTableResponse](ctx, q, "POST", "/tables?appId="+q.AppId, table)
+}
 
-	return field
+func (q *Quickbase) CreateField(ctx context.Context, tableId string, field CreateFieldBody) (CreateFieldResponse, error) {
+	return CallREST[CreateFieldResponse](ctx, q, "POST", "/fields?tableId="+tableId, field)
 }
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	AUTH_USER_TOKEN = "user-token"
+	AUTH_APP_TOKEN  = "app-token"
+	AUTH_TEMPORARY  = "temporary"
+
+	// Temporary tokens are valid for five minutes, they are renewed a little earlier
+	TEMPORARY_TOKEN_TTL = 4*time.Minute + 30*time.Second
+)
+
+// XMLToken is an element added to the qdbapi element of an XML request
+type XMLToken struct {
+	Name  string
+	Value string
+}
+
+// Auth authenticates the REST and XML requests of an app
+type Auth interface {
+	RESTHeaders(ctx context.Context, q *Quickbase, header http.Header) error
+	XMLTokens(ctx context.Context, q *Quickbase) ([]XMLToken, error)
+}
+
+// UserTokenAuth sends a user token with every request
+type UserTokenAuth struct {
+	UserToken string
+}
+
+func (a UserTokenAuth) RESTHeaders(ctx context.Context, q *Quickbase, header http.Header) error {
+	header.Set("Authorization", "QB-USER-TOKEN "+a.UserToken)
+
+	return nil
+}
+
+func (a UserTokenAuth) XMLTokens(ctx context.Context, q *Quickbase) ([]XMLToken, error) {
+	return []XMLToken{{Name: "usertoken", Value: a.UserToken}}, nil
+}
+
+// AppTokenAuth sends a user token along with the app token required by the app
+type AppTokenAuth struct {
+	UserToken string
+	AppToken  string
+}
+
+func (a AppTokenAuth) RESTHeaders(ctx context.Context, q *Quickbase, header http.Header) error {
+	header.Set("Authorization", "QB-USER-TOKEN "+a.UserToken)
+	header.Set("QB-App-Token", a.AppToken)
+
+	return nil
+}
+
+func (a AppTokenAuth) XMLTokens(ctx context.Context, q *Quickbase) ([]XMLToken, error) {
+	return []XMLToken{{Name: "usertoken", Value: a.UserToken}, {Name: "apptoken", Value: a.AppToken}}, nil
+}
+
+// TemporaryTokenAuth exchanges the session ticket of an SSO realm for temporary tokens scoped to the app
+type TemporaryTokenAuth struct {
+	Ticket   string
+	AppToken string
+
+	mutex   sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Temporary token of the app, fetched again once it is about to expire
+func (a *TemporaryTokenAuth) temporaryToken(ctx context.Context, q *Quickbase) (string, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.token != "" && time.Now().Before(a.expires) {
+		return a.token, nil
+	}
+
+	path := "/auth/temporary/" + q.AppId
+	req, err := http.NewRequestWithContext(ctx, "GET", REST_BASE_URL+path, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	req.Header = http.Header{
+		"QB-Realm-Hostname": {q.Realm},
+		"User-Agent":        {USER_AGENT},
+	}
+
+	req.AddCookie(&http.Cookie{Name: "TICKET", Value: a.Ticket})
+
+	if a.AppToken != "" {
+		req.Header.Set("QB-App-Token", a.AppToken)
+	}
+
+	res, err := q.client().Do(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		restErr := &RESTError{Method: "GET", Path: path, Status: res.StatusCode}
+
+		json.NewDecoder(res.Body).Decode(restErr)
+
+		return "", restErr
+	}
+
+	var response struct {
+		TemporaryAuthorization string `json:"temporaryAuthorization"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return "", err
+	}
+
+	a.token = response.TemporaryAuthorization
+	a.expires = time.Now().Add(TEMPORARY_TOKEN_TTL)
+
+	return a.token, nil
+}
+
+func (a *TemporaryTokenAuth) RESTHeaders(ctx context.Context, q *Quickbase, header http.Header) error {
+	token, err := a.temporaryToken(ctx, q)
+
+	if err != nil {
+		return err
+	}
+
+	header.Set("Authorization", "QB-TEMP-TOKEN "+token)
+
+	return nil
+}
+
+// The XML API does not accept temporary tokens, so the session ticket is sent instead
+func (a *TemporaryTokenAuth) XMLTokens(ctx context.Context, q *Quickbase) ([]XMLToken, error) {
+	tokens := []XMLToken{{Name: "ticket", Value: a.Ticket}}
+
+	if a.AppToken != "" {
+		tokens = append(tokens, XMLToken{Name: "apptoken", Value: a.AppToken})
+	}
+
+	return tokens, nil
+}
+
+// Creates the auth of an environment, an empty kind uses an app token when one is given and a user token otherwise
+func NewAuth(kind string, userToken string, appToken string, ticket string) (Auth, error) {
+	switch kind {
+	case "":
+		if appToken != "" {
+			return AppTokenAuth{UserToken: userToken, AppToken: appToken}, nil
+		}
+
+		return UserTokenAuth{UserToken: userToken}, nil
+	case AUTH_USER_TOKEN:
+		return UserTokenAuth{UserToken: userToken}, nil
+	case AUTH_APP_TOKEN:
+		if appToken == "" {
+			return nil, errors.New("auth " + AUTH_APP_TOKEN + " needs an appToken")
+		}
+
+		return AppTokenAuth{UserToken: userToken, AppToken: appToken}, nil
+	case AUTH_TEMPORARY:
+		if ticket == "" {
+			return nil, errors.New("auth " + AUTH_TEMPORARY + " needs a ticket")
+		}
+
+		return &TemporaryTokenAuth{Ticket: ticket, AppToken: appToken}, nil
+	}
+
+	return nil, errors.New("auth must be " + AUTH_USER_TOKEN + ", " + AUTH_APP_TOKEN + " or " + AUTH_TEMPORARY)
+}
+
+// Auth of the app, derived from its tokens when none was set
+func (q *Quickbase) auth() Auth {
+	if q.Auth != nil {
+		return q.Auth
+	}
+
+	auth, _ := NewAuth("", q.UserToken, q.AppToken, "")
+
+	return auth
+}
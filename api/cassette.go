@@ -0,0 +1,311 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const REDACTED = "REDACTED"
+
+var (
+	// Headers carrying tokens or session tickets
+	secretHeaders = []string{"Authorization", "Qb-App-Token", "Qb-Temp-Token", "Cookie", "Set-Cookie"}
+
+	xmlTokenRegex       = regexp.MustCompile(`<(usertoken|apptoken|ticket)>[^<]*</(usertoken|apptoken|ticket)>`)
+	temporaryTokenRegex = regexp.MustCompile(`"temporaryAuthorization"\s*:\s*"[^"]*"`)
+	fileNameRegex       = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Interaction is a request and its response as saved in a cassette file
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// Cassette records or replays the requests of the clients whose transport it wraps
+type Cassette interface {
+	Wrap(next http.RoundTripper) http.RoundTripper
+}
+
+func redactHeader(header http.Header) http.Header {
+	redacted := header.Clone()
+
+	for _, name := range secretHeaders {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, REDACTED)
+		}
+	}
+
+	return redacted
+}
+
+// Redacts the tokens of the API envelopes, then any other secret the redact function knows about
+func redactBody(body string, redact func(string) string) string {
+	body = xmlTokenRegex.ReplaceAllString(body, "<$1>"+REDACTED+"</$2>")
+	body = temporaryTokenRegex.ReplaceAllString(body, `"temporaryAuthorization":"`+REDACTED+`"`)
+
+	if redact != nil {
+		body = redact(body)
+	}
+
+	return body
+}
+
+// Value of a header, including headers set with a non canonical name like QUICKBASE-ACTION
+func headerValue(header http.Header, name string) string {
+	if value := header.Get(name); value != "" {
+		return value
+	}
+
+	if values := header[name]; len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// Reads the body of a request without consuming it
+func requestBody(req *http.Request) (*http.Request, string, error) {
+	if req.Body == nil {
+		return req, "", nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+
+	if err != nil {
+		return req, "", err
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return req, string(body), nil
+}
+
+// Requests are matched on what identifies the call, tokens and other headers are left out
+func interactionKey(request RecordedRequest) string {
+	return strings.Join([]string{
+		request.Method,
+		request.URL,
+		headerValue(request.Header, "QB-Realm-Hostname"),
+		headerValue(request.Header, "QUICKBASE-ACTION"),
+		request.Body,
+	}, "\n")
+}
+
+// Recorder saves every interaction to a numbered file in its directory, with tokens redacted
+type Recorder struct {
+	Dir string
+
+	// Redact removes secrets found outside the token elements and headers, like tokens resolved into a page
+	Redact func(string) string
+
+	mutex sync.Mutex
+	count int
+}
+
+type recordingTransport struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entries) > 0 {
+		return nil, errors.New("record directory " + dir + " is not empty")
+	}
+
+	return &Recorder{Dir: dir}, nil
+}
+
+func (r *Recorder) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &recordingTransport{recorder: r, next: next}
+}
+
+func (r *Recorder) save(interaction Interaction) error {
+	r.mutex.Lock()
+	r.count += 1
+	count := r.count
+	r.mutex.Unlock()
+
+	name := headerValue(interaction.Request.Header, "QUICKBASE-ACTION")
+
+	if name == "" {
+		name = strings.Split(strings.TrimPrefix(interaction.Request.URL, REST_BASE_URL+"/"), "?")[0]
+	}
+
+	fileName := fmt.Sprintf("%05d-%s-%s.json", count, interaction.Request.Method, strings.Trim(fileNameRegex.ReplaceAllString(name, "_"), "_"))
+	content, err := json.MarshalIndent(interaction, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(r.Dir, fileName), content, 0644)
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, body, err := requestBody(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.next.RoundTrip(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(content))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: redactHeader(req.Header),
+			Body:   redactBody(body, t.recorder.Redact),
+		},
+		Response: RecordedResponse{
+			Status: res.StatusCode,
+			Header: redactHeader(res.Header),
+			Body:   redactBody(string(content), t.recorder.Redact),
+		},
+	}
+
+	if err := t.recorder.save(interaction); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Replayer answers requests from a recorded directory instead of the network
+type Replayer struct {
+	// Redact must match the function the requests were recorded with, as requests are matched on their redacted body
+	Redact func(string) string
+
+	mutex        sync.Mutex
+	interactions map[string][]Interaction
+	served       map[string]int
+}
+
+// Loads the interactions of a directory, requests made several times are answered in the order they were recorded
+func NewReplayer(dir string) (*Replayer, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("replay directory " + dir + " has no recorded requests")
+	}
+
+	sort.Strings(files)
+
+	r := &Replayer{interactions: make(map[string][]Interaction), served: make(map[string]int)}
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var interaction Interaction
+
+		if err := json.Unmarshal(content, &interaction); err != nil {
+			return nil, fmt.Errorf("%s is not a recorded request: %w", file, err)
+		}
+
+		key := interactionKey(interaction.Request)
+		r.interactions[key] = append(r.interactions[key], interaction)
+	}
+
+	return r, nil
+}
+
+func (r *Replayer) Wrap(next http.RoundTripper) http.RoundTripper {
+	return r
+}
+
+// Once every recording of a request has been served the last one is repeated
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A replayed call is cancelled like a network call would be
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	req, body, err := requestBody(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key := interactionKey(RecordedRequest{Method: req.Method, URL: req.URL.String(), Header: req.Header, Body: redactBody(body, r.Redact)})
+
+	r.mutex.Lock()
+	recorded := r.interactions[key]
+	index := min(r.served[key], len(recorded)-1)
+	r.served[key] += 1
+	r.mutex.Unlock()
+
+	if len(recorded) == 0 {
+		return nil, errors.New("no recorded response for " + req.Method + " " + req.URL.String() + " " + headerValue(req.Header, "QUICKBASE-ACTION"))
+	}
+
+	response := recorded[index].Response
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.Status, http.StatusText(response.Status)),
+		StatusCode:    response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        response.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}, nil
+}
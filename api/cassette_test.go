@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type staticTransport struct {
+	body string
+}
+
+func (t staticTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: 200, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(t.body))}, nil
+}
+
+func TestCassetteRedactsResolvedSecrets(t *testing.T) {
+	const secret = "b9xk2_resolved_user_token"
+
+	redact := func(text string) string {
+		return strings.ReplaceAll(text, secret, "b9xk2_*")
+	}
+
+	dir := t.TempDir()
+	recorder, err := NewRecorder(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.Redact = redact
+
+	auth, err := NewAuth("", "b1_own_token", "", "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page := `fetch("/db/bsrc00001?a=API_DoQuery&usertoken=` + secret + `")`
+	response := `<qdbapi><action>API_GetDBPage</action><errcode>0</errcode><errtext>No error</errtext><pagebody>` + strings.ReplaceAll(page, "&", "&amp;") + `</pagebody></qdbapi>`
+	q := Quickbase{AppId: "bsrc00000", Realm: "source.quickbase.com", Auth: auth, Client: &http.Client{Transport: recorder.Wrap(staticTransport{body: response})}}
+
+	if _, err := q.ReplacePage(context.Background(), "3", page); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	if err != nil || len(files) != 1 {
+		t.Fatalf("recorded files = %v, %v", files, err)
+	}
+
+	content, err := os.ReadFile(files[0])
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(content), secret) {
+		t.Errorf("recording contains the resolved secret:\n%s", content)
+	}
+
+	replayer, err := NewReplayer(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayer.Redact = redact
+	q.Client = &http.Client{Transport: replayer}
+
+	if _, err := q.ReplacePage(context.Background(), "3", page); err != nil {
+		t.Errorf("replaying the request with the secret failed: %v", err)
+	}
+}
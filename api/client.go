@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Deadline of a single request when the client does not set one
+const DEFAULT_REQUEST_TIMEOUT = 60 * time.Second
+
+// Client shared by apps which do not set their own
+var defaultClient = NewHTTPClient()
+
+// NewHTTPClient returns a client whose transport keeps enough idle connections for the concurrent calls of a run
+func NewHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 32
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.TLSHandshakeTimeout = 10 * time.Second
+
+	return &http.Client{Transport: transport}
+}
+
+func (q *Quickbase) client() *http.Client {
+	if q.Client != nil {
+		return q.Client
+	}
+
+	return defaultClient
+}
+
+// Context of a single call, cancelled with the run or once the request timeout has passed
+func (q *Quickbase) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := q.RequestTimeout
+
+	if timeout <= 0 {
+		timeout = DEFAULT_REQUEST_TIMEOUT
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
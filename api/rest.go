@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const (
+	REST_BASE_URL = "https://api.quickbase.com/v1"
+	USER_AGENT    = "app-configuration"
+)
+
+// RESTError is returned by CallREST when Quickbase answers with an error status
+type RESTError struct {
+	Method      string
+	Path        string
+	Status      int
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+func (e *RESTError) Error() string {
+	message := fmt.Sprintf("%s %s failed with status %d", e.Method, e.Path, e.Status)
+
+	if e.Message != "" {
+		message += ": " + e.Message
+	}
+
+	if e.Description != "" {
+		message += " (" + e.Description + ")"
+	}
+
+	return message
+}
+
+// Sends a request to the REST API, encoding the body as JSON when given and decoding the response into Res
+func CallREST[Res any](ctx context.Context, q *Quickbase, method string, path string, body any) (Res, error) {
+	var response Res
+	var reader io.Reader
+
+	ctx, cancel := q.callContext(ctx)
+	defer cancel()
+
+	if body != nil {
+		content, err := json.Marshal(body)
+
+		if err != nil {
+			return response, err
+		}
+
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, REST_BASE_URL+path, reader)
+
+	if err != nil {
+		return response, err
+	}
+
+	req.Header = http.Header{
+		"QB-Realm-Hostname": {q.Realm},
+		"User-Agent":        {USER_AGENT},
+	}
+
+	if err := q.auth().RESTHeaders(ctx, q, req.Header); err != nil {
+		return response, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := q.client().Do(req)
+
+	if err != nil {
+		return response, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		restErr := &RESTError{Method: method, Path: path, Status: res.StatusCode}
+
+		json.NewDecoder(res.Body).Decode(restErr)
+
+		return response, restErr
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil && err != io.EOF {
+		return response, fmt.Errorf("%s %s returned an invalid response: %w", method, path, err)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCallRESTStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		response    string
+		wantFields  int
+		wantErr     *RESTError
+		wantMessage string
+	}{
+		{
+			name:       "success",
+			status:     200,
+			response:   `[{"id":6,"label":"Status","fieldType":"text"},{"id":7,"label":"Due","fieldType":"date"}]`,
+			wantFields: 2,
+		},
+		{
+			name:   "success without a body",
+			status: 204,
+		},
+		{
+			name:        "error with message and description",
+			status:      401,
+			response:    `{"message":"Access denied","description":"User token is invalid"}`,
+			wantErr:     &RESTError{Method: "GET", Path: "/fields?tableId=bsrc00001", Status: 401, Message: "Access denied", Description: "User token is invalid"},
+			wantMessage: "GET /fields?tableId=bsrc00001 failed with status 401: Access denied (User token is invalid)",
+		},
+		{
+			name:        "error without a JSON body",
+			status:      502,
+			response:    `<html>Bad Gateway</html>`,
+			wantErr:     &RESTError{Method: "GET", Path: "/fields?tableId=bsrc00001", Status: 502},
+			wantMessage: "GET /fields?tableId=bsrc00001 failed with status 502",
+		},
+		{
+			name:        "redirect is not a success",
+			status:      304,
+			wantErr:     &RESTError{Method: "GET", Path: "/fields?tableId=bsrc00001", Status: 304},
+			wantMessage: "GET /fields?tableId=bsrc00001 failed with status 304",
+		},
+		{
+			name:        "invalid JSON on success",
+			status:      200,
+			response:    `{"id":`,
+			wantMessage: "GET /fields?tableId=bsrc00001 returned an invalid response",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent *http.Request
+
+			q := &Quickbase{AppId: "bsrc00000", Realm: "source.quickbase.com", Auth: UserTokenAuth{UserToken: "b1_user"}, Client: &http.Client{
+				Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					sent = req
+
+					return textResponse(test.status, test.response), nil
+				}),
+			}}
+
+			fields, err := q.GetFields(context.Background(), "bsrc00001")
+
+			if headerValue(sent.Header, "QB-Realm-Hostname") != "source.quickbase.com" || headerValue(sent.Header, "Authorization") != "QB-USER-TOKEN b1_user" {
+				t.Errorf("request headers = %v", sent.Header)
+			}
+
+			if test.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("GetFields() error = %v", err)
+				}
+
+				if len(fields) != test.wantFields {
+					t.Errorf("GetFields() returned %d fields, want %d", len(fields), test.wantFields)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.HasPrefix(err.Error(), test.wantMessage) {
+				t.Fatalf("GetFields() error = %v, want %s", err, test.wantMessage)
+			}
+
+			var restErr *RESTError
+
+			if test.wantErr == nil {
+				if errors.As(err, &restErr) {
+					t.Errorf("invalid response decoded as %+v", restErr)
+				}
+
+				return
+			}
+
+			if !errors.As(err, &restErr) || *restErr != *test.wantErr {
+				t.Errorf("GetFields() error = %#v, want %#v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryRecordsPagination(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		pageLimit int
+		failAt    int
+		wantSkips []int
+		wantErr   bool
+	}{
+		{
+			name:      "empty table",
+			total:     0,
+			pageLimit: RECORDS_PAGE_SIZE,
+			wantSkips: []int{0},
+		},
+		{
+			name:      "single page",
+			total:     3,
+			pageLimit: RECORDS_PAGE_SIZE,
+			wantSkips: []int{0},
+		},
+		{
+			name:      "several full pages",
+			total:     2500,
+			pageLimit: RECORDS_PAGE_SIZE,
+			wantSkips: []int{0, 1000, 2000},
+		},
+		{
+			name:      "server returns smaller pages",
+			total:     1000,
+			pageLimit: 400,
+			wantSkips: []int{0, 400, 800},
+		},
+		{
+			name:      "failed page keeps the records read before",
+			total:     2500,
+			pageLimit: RECORDS_PAGE_SIZE,
+			failAt:    1000,
+			wantSkips: []int{0, 1000},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			skips := make([]int, 0)
+
+			q := &Quickbase{AppId: "bsrc00000", Realm: "source.quickbase.com", Auth: UserTokenAuth{UserToken: "b1_user"}, Client: &http.Client{
+				Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					var body QueryRecordsBody
+
+					if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+						return nil, err
+					}
+
+					skips = append(skips, body.Options.Skip)
+
+					if test.failAt > 0 && body.Options.Skip == test.failAt {
+						return textResponse(429, `{"message":"Too many requests"}`), nil
+					}
+
+					count := max(0, min(test.pageLimit, body.Options.Top, test.total-body.Options.Skip))
+					data := make([]string, count)
+
+					for i := range data {
+						data[i] = fmt.Sprintf(`{"3":{"value":%d}}`, body.Options.Skip+i+1)
+					}
+
+					return textResponse(200, fmt.Sprintf(`{"data":[%s],"metadata":{"numRecords":%d,"skip":%d,"totalRecords":%d}}`,
+						strings.Join(data, ","), count, body.Options.Skip, test.total)), nil
+				}),
+			}}
+
+			records, err := q.QueryRecords(context.Background(), "bsrc00001", []int{3})
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("QueryRecords() error = %v, want error %v", err, test.wantErr)
+			}
+
+			if !reflect.DeepEqual(skips, test.wantSkips) {
+				t.Errorf("requested skips %v, want %v", skips, test.wantSkips)
+			}
+
+			wantRecords := test.total
+
+			if test.failAt > 0 {
+				wantRecords = test.failAt
+			}
+
+			if len(records) != wantRecords {
+				t.Errorf("QueryRecords() returned %d records, want %d", len(records), wantRecords)
+			}
+		})
+	}
+}
+
+func TestCreateMethodsReturnRESTErrors(t *testing.T) {
+	q := &Quickbase{AppId: "btgt00000", Realm: "target.quickbase.com", Auth: UserTokenAuth{UserToken: "b1_user"}, Client: &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return textResponse(400, `{"message":"Bad Request","description":"Label is already used"}`), nil
+		}),
+	}}
+
+	calls := map[string]func() error{
+		"CreateTable": func() error {
+			_, err := q.CreateTable(context.Background(), CreateTableBody{Name: "Orders"})
+			return err
+		},
+		"CreateField": func() error {
+			_, err := q.CreateField(context.Background(), "btgt00001", CreateFieldBody{Label: "Status", FieldType: "text"})
+			return err
+		},
+		"CreateRelationship": func() error {
+			_, err := q.CreateRelationship(context.Background(), "btgt00001", CreateRelationshipBody{ParentTableID: "btgt00002"})
+			return err
+		},
+		"UpsertRecords": func() error {
+			_, err := q.UpsertRecords(context.Background(), "btgt00001", []Record{}, 6)
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			var restErr *RESTError
+
+			if err := call(); !errors.As(err, &restErr) || restErr.Status != 400 || restErr.Description != "Label is already used" {
+				t.Errorf("%s() error = %v, want the 400 RESTError", name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// XMLEnvelope holds the error fields every XML API response has
+type XMLEnvelope struct {
+	ErrorCode   string `xml:"errcode"`
+	ErrorText   string `xml:"errtext"`
+	ErrorDetail string `xml:"errdetail"`
+}
+
+// XMLError is returned by CallXML when Quickbase answers with a non-zero errcode
+type XMLError struct {
+	Action string
+	Code   string
+	Text   string
+	Detail string
+}
+
+func (e *XMLError) Error() string {
+	message := fmt.Sprintf("%s failed with error %s: %s", e.Action, e.Code, e.Text)
+
+	if e.Detail != "" {
+		message += " (" + e.Detail + ")"
+	}
+
+	return message
+}
+
+// Request body with the tokens of the app injected after the qdbapi element
+func xmlRequestBody(ctx context.Context, q *Quickbase, request any) ([]byte, error) {
+	body, err := xml.MarshalIndent(request, " ", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	xmlTokens, err := q.auth().XMLTokens(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens bytes.Buffer
+
+	for _, token := range xmlTokens {
+		if token.Value == "" {
+			continue
+		}
+
+		tokens.WriteString("<" + token.Name + ">")
+		xml.EscapeText(&tokens, []byte(token.Value))
+		tokens.WriteString("</" + token.Name + ">")
+	}
+
+	if empty := []byte("<qdbapi></qdbapi>"); bytes.Equal(bytes.TrimSpace(body), empty) {
+		return append(append([]byte("<qdbapi>"), tokens.Bytes()...), []byte("</qdbapi>")...), nil
+	}
+
+	return bytes.Replace(body, []byte("<qdbapi>"), append([]byte("<qdbapi>"), tokens.Bytes()...), 1), nil
+}
+
+// Posts an XML API action to a DBID, the request must marshal to a qdbapi element and the tokens are added to it
+func CallXML[Req any, Res any](ctx context.Context, q *Quickbase, dbid string, action string, request Req) (Res, error) {
+	var response Res
+
+	ctx, cancel := q.callContext(ctx)
+	defer cancel()
+
+	body, err := xmlRequestBody(ctx, q, request)
+
+	if err != nil {
+		return response, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://"+q.Realm+"/db/"+dbid, bytes.NewReader(body))
+
+	if err != nil {
+		return response, err
+	}
+
+	req.Header = http.Header{
+		"Content-Type":     {"application/xml"},
+		"QUICKBASE-ACTION": {action},
+	}
+
+	res, err := q.client().Do(req)
+
+	if err != nil {
+		return response, err
+	}
+
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return response, err
+	}
+
+	var envelope XMLEnvelope
+
+	if err := xml.Unmarshal(content, &envelope); err != nil {
+		return response, fmt.Errorf("%s returned an invalid response: %w", action, err)
+	}
+
+	if err := xml.Unmarshal(content, &response); err != nil {
+		return response, fmt.Errorf("%s returned an invalid response: %w", action, err)
+	}
+
+	if envelope.ErrorCode != "0" {
+		return response, &XMLError{Action: action, Code: envelope.ErrorCode, Text: envelope.ErrorText, Detail: envelope.ErrorDetail}
+	}
+
+	return response, nil
+}
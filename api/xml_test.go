@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Transport answering every request with a function, for tests which check what was sent
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func textResponse(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestCallXMLEnvelope(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     Auth
+		call     func(q *Quickbase) error
+		wantURL  string
+		wantBody []string
+	}{
+		{
+			name: "empty request gets the tokens only",
+			auth: UserTokenAuth{UserToken: "b1_user"},
+			call: func(q *Quickbase) error {
+				_, err := q.GetSchema(context.Background(), "bsrc00001")
+				return err
+			},
+			wantURL:  "https://source.quickbase.com/db/bsrc00001",
+			wantBody: []string{"<qdbapi><usertoken>b1_user</usertoken></qdbapi>"},
+		},
+		{
+			name: "tokens go before the request fields",
+			auth: AppTokenAuth{UserToken: "b1_user", AppToken: "app_1"},
+			call: func(q *Quickbase) error {
+				_, err := q.SetDBVar(context.Background(), "ordersTable", "btgt00001")
+				return err
+			},
+			wantURL: "https://source.quickbase.com/db/bsrc00000",
+			wantBody: []string{
+				"<qdbapi><usertoken>b1_user</usertoken><apptoken>app_1</apptoken>",
+				"<varname>ordersTable</varname>",
+				"<value>btgt00001</value>",
+			},
+		},
+		{
+			name: "token and page body are escaped",
+			auth: UserTokenAuth{UserToken: "b1_<user>&"},
+			call: func(q *Quickbase) error {
+				_, err := q.ReplacePage(context.Background(), "3", `<a href="?a=q&qid=5">`)
+				return err
+			},
+			wantURL: "https://source.quickbase.com/db/bsrc00000",
+			wantBody: []string{
+				"<usertoken>b1_&lt;user&gt;&amp;</usertoken>",
+				"<pagebody>&lt;a href=&#34;?a=q&amp;qid=5&#34;&gt;</pagebody>",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent *http.Request
+			var body string
+
+			q := &Quickbase{AppId: "bsrc00000", Realm: "source.quickbase.com", Auth: test.auth, Client: &http.Client{
+				Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					content, err := io.ReadAll(req.Body)
+					sent, body = req, string(content)
+
+					return textResponse(200, "<qdbapi><errcode>0</errcode><errtext>No error</errtext></qdbapi>"), err
+				}),
+			}}
+
+			if err := test.call(q); err != nil {
+				t.Fatal(err)
+			}
+
+			if sent.URL.String() != test.wantURL {
+				t.Errorf("URL = %s, want %s", sent.URL, test.wantURL)
+			}
+
+			if sent.Method != "POST" || headerValue(sent.Header, "Content-Type") != "application/xml" {
+				t.Errorf("sent %s with content type %q, want a POST of application/xml", sent.Method, headerValue(sent.Header, "Content-Type"))
+			}
+
+			for _, want := range test.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %s:\n%s", want, body)
+				}
+			}
+		})
+	}
+}
+
+func TestCallXMLErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		wantErr     *XMLError
+		wantMessage string
+	}{
+		{
+			name:     "no error",
+			response: `<?xml version="1.0" ?><qdbapi><action>API_GetDBvar</action><errcode>0</errcode><errtext>No error</errtext><value>1</value></qdbapi>`,
+		},
+		{
+			name:        "error code and text",
+			response:    `<qdbapi><action>API_GetDBvar</action><errcode>4</errcode><errtext>User not authorized</errtext></qdbapi>`,
+			wantErr:     &XMLError{Action: "API_GetDBvar", Code: "4", Text: "User not authorized"},
+			wantMessage: "API_GetDBvar failed with error 4: User not authorized",
+		},
+		{
+			name:        "error detail",
+			response:    `<qdbapi><errcode>31</errcode><errtext>No such variable</errtext><errdetail>Variable missing was not found</errdetail></qdbapi>`,
+			wantErr:     &XMLError{Action: "API_GetDBvar", Code: "31", Text: "No such variable", Detail: "Variable missing was not found"},
+			wantMessage: "API_GetDBvar failed with error 31: No such variable (Variable missing was not found)",
+		},
+		{
+			name:        "missing error code",
+			response:    `<qdbapi><value>1</value></qdbapi>`,
+			wantErr:     &XMLError{Action: "API_GetDBvar"},
+			wantMessage: "API_GetDBvar failed with error : ",
+		},
+		{
+			name:        "not XML",
+			response:    `<html><body>Bad Gateway</html>`,
+			wantMessage: "API_GetDBvar returned an invalid response",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := &Quickbase{AppId: "bsrc00000", Realm: "source.quickbase.com", Auth: UserTokenAuth{UserToken: "b1_user"}, Client: &http.Client{
+				Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					return textResponse(200, test.response), nil
+				}),
+			}}
+
+			response, err := q.GetDBVar(context.Background(), "ordersTable")
+
+			if test.wantMessage == "" {
+				if err != nil {
+					t.Fatalf("GetDBVar() error = %v", err)
+				}
+
+				if response.Value != "1" {
+					t.Errorf("value = %q, want 1", response.Value)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.HasPrefix(err.Error(), test.wantMessage) {
+				t.Fatalf("GetDBVar() error = %v, want %s", err, test.wantMessage)
+			}
+
+			var xmlErr *XMLError
+
+			if test.wantErr == nil {
+				if errors.As(err, &xmlErr) {
+					t.Errorf("invalid response decoded as %+v", xmlErr)
+				}
+
+				return
+			}
+
+			if !errors.As(err, &xmlErr) || *xmlErr != *test.wantErr {
+				t.Errorf("GetDBVar() error = %#v, want %#v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"app-configuration/api"
+	"errors"
+	"log"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Records or replays the requests of both environments, nil when the network is used as is
+var runCassette api.Cassette
+
+var recordFlag = &cli.StringFlag{
+	Name:  "record",
+	Usage: "Save every Quickbase request and response to this directory, with tokens redacted",
+}
+
+var replayFlag = &cli.StringFlag{
+	Name:  "replay",
+	Usage: "Answer Quickbase requests from a directory saved with --record instead of the network",
+}
+
+func StartCassette(ctx *cli.Context) error {
+	record, replay := ctx.String(recordFlag.Name), ctx.String(replayFlag.Name)
+
+	if record != "" && replay != "" {
+		return errors.New("--record and --replay cannot be used together")
+	}
+
+	if record != "" {
+		recorder, err := api.NewRecorder(record)
+
+		if err != nil {
+			return err
+		}
+
+		recorder.Redact = Redact
+		runCassette = recorder
+		log.Println(warningStyle.Render("Recording Quickbase requests to " + record))
+	}
+
+	if replay != "" {
+		replayer, err := api.NewReplayer(replay)
+
+		if err != nil {
+			return err
+		}
+
+		replayer.Redact = Redact
+		runCassette = replayer
+		log.Println(warningStyle.Render("Replaying Quickbase requests from " + replay))
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplayVarsPush(t *testing.T) {
+	cassette, err := filepath.Abs(filepath.Join("testdata", "replay", "vars_push"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	config := `{
+  "source": {"id": "bsrc00000", "token": "b12345_source_token", "realm": "source.quickbase.com"},
+  "target": {"id": "btgt00000", "token": "b67890_target_token", "realm": "target.quickbase.com"}
+}`
+
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout, _ = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+
+	t.Cleanup(func() {
+		os.Stdout = stdout
+		os.Chdir(cwd)
+		runCassette, runReport, runErr = nil, nil, nil
+	})
+
+	args := []string{"app-configuration", "--output", "json", "--replay", cassette, "vars", "push", "--var", "ordersTable"}
+
+	if err := NewApp().RunContext(context.Background(), args); err != nil {
+		t.Fatalf("vars push failed: %v", err)
+	}
+
+	if manifest.Status != "completed" {
+		t.Errorf("run status = %q, want completed", manifest.Status)
+	}
+
+	if len(runReport.Errors) > 0 {
+		t.Errorf("report has errors: %v", runReport.Errors)
+	}
+
+	pushed := false
+
+	for _, result := range runReport.Results {
+		if result.Phase == PHASE_VARIABLES && result.Item == "ordersTable" {
+			pushed = result.Status == "ok"
+		}
+	}
+
+	if !pushed {
+		t.Errorf("ordersTable was not pushed: %+v", runReport.Results)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, JOURNAL_FILENAME)); !os.IsNotExist(err) {
+		t.Errorf("replayed writes were journaled")
+	}
+}
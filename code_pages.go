@@ -11,30 +11,93 @@ import (
 	"sync"
 )
 
-func SavePages(sourceConfig api.Quickbase) {
+// Names of the configured code pages by ID, the schema is only read when pages are filtered as filters may be names
+func configuredPageNames(sourceConfig api.Quickbase) (map[string]string, error) {
+	names := make(map[string]string)
+
+	for _, pageId := range config.ReadConfig().Pages {
+		names[strconv.Itoa(pageId)] = ""
+	}
+
+	if len(runScope.Pages) == 0 {
+		return names, nil
+	}
+
+	schema, err := sourceConfig.GetSchema(runContext, sourceConfig.AppId)
+
+	if err != nil {
+		return names, err
+	}
+
+	for _, page := range schema.Table.Pages {
+		if _, ok := names[page.ID]; ok {
+			names[page.ID] = page.Name()
+		}
+	}
+
+	runScope.WarnUnmatchedPages(names)
+
+	return names, nil
+}
+
+func SavePages(sourceConfig api.Quickbase) error {
 	log.Println(boldLogStyle.Render("Processing code pages"))
 
 	var wg sync.WaitGroup
 	config := config.ReadConfig()
+	names, err := configuredPageNames(sourceConfig)
+
+	if err != nil {
+		return err
+	}
+
+	pageIds := make([]int, 0)
 
 	for _, pageId := range config.Pages {
+		if runScope.IncludesPage(strconv.Itoa(pageId), names[strconv.Itoa(pageId)]) {
+			pageIds = append(pageIds, pageId)
+		}
+	}
+
+	runProgress.Start(PHASE_PAGES_FETCH, len(pageIds))
+
+	for _, pageId := range pageIds {
+		if runContext.Err() != nil {
+			break
+		}
+
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			var pageErr error
+
+			defer func() {
+				runProgress.Done(PHASE_PAGES_FETCH, strconv.Itoa(pageId), pageErr)
+			}()
+
+			runProgress.Begin(PHASE_PAGES_FETCH, strconv.Itoa(pageId))
 
 			log.Println(logStyle.Render("Saving Code Page -- " + strconv.Itoa(pageId)))
 
 			strPageId := strconv.Itoa(pageId)
-			res := sourceConfig.GetPage(strPageId)
-			filemanager.SaveFile("pages/source/"+strPageId+".txt", strings.TrimSpace(res.PageBody))
+			res, pageErr := sourceConfig.GetPage(runContext, strPageId)
+
+			if pageErr != nil {
+				return
+			}
+
+			content := ProtectSecrets(strings.TrimSpace(res.PageBody), sourceConfig, "Code Page "+strPageId)
+			filemanager.SaveFile(workspace.Path("pages", "source", strPageId+".txt"), content)
 		}()
 	}
 
 	wg.Wait()
+
+	return nil
 }
 
-func ReplacePages(targetConfig api.Quickbase) {
-	files, err := os.ReadDir("pages/source")
+func ReplacePages(sourceConfig api.Quickbase, targetConfig api.Quickbase) {
+	files, err := os.ReadDir(workspace.Path("pages", "source"))
 
 	if err != nil {
 		log.Fatal(errorStyle.Render(err.Error()))
@@ -42,34 +105,73 @@ func ReplacePages(targetConfig api.Quickbase) {
 
 	var wg sync.WaitGroup
 
+	reports := ReadReportMapping()
+	aliases := sourceTableAliases(sourceConfig)
+
+	runProgress.Start(PHASE_PAGES_REPLACE, len(files))
+
 	// Looping through each file
 	for _, file := range files {
+		if runContext.Err() != nil {
+			break
+		}
+
 		wg.Add(1)
 
 		go func() {
 			defer wg.Done()
+			var pageErr error
 
-			content := filemanager.ReadFile("pages/source/" + file.Name())
+			defer func() {
+				runProgress.Done(PHASE_PAGES_REPLACE, file.Name(), pageErr)
+			}()
 
-			mapping := filemanager.ReadMapping()
-			flag := false
+			runProgress.Begin(PHASE_PAGES_REPLACE, file.Name())
+
+			content := filemanager.ReadFile(workspace.Path("pages", "source", file.Name()))
+			pageId := strings.TrimSuffix(file.Name(), ".txt")
+			sourceContent := ResolveSecrets(RenderTemplate(content, sourceConfig, "Code Page "+pageId), sourceConfig, "Code Page "+pageId)
+
+			mapping := filemanager.ReadMapping(workspace.Path("mapping", "mapping.json"))
+
+			// Report IDs are rewritten first as they are matched by the source DBIDs before them
+			content = RewriteReportIds(content, "", reports, aliases)
 
 			// Replacing content
 			for source, target := range mapping {
 				if strings.Contains(content, source) {
-					flag = true
 					content = strings.ReplaceAll(content, source, target)
 				}
 			}
 
-			if flag {
-				pageId := strings.TrimSuffix(file.Name(), ".txt")
+			// Placeholders are only rendered when pushing, the saved page keeps them
+			pushContent := ResolveSecrets(RenderTemplate(content, targetConfig, "Code Page "+pageId), targetConfig, "Code Page "+pageId)
 
+			if pushContent != sourceContent {
 				log.Println(logStyle.Render("Updating Code Page -- " + pageId))
 
-				targetConfig.ReplacePage(pageId, content)
-				filemanager.SaveFile("pages/target/"+file.Name(), content)
-				flag = false
+				var before api.GetPageResponse
+				before, pageErr = targetConfig.GetPage(runContext, pageId)
+
+				if pageErr != nil {
+					return
+				}
+
+				_, pageErr = targetConfig.ReplacePage(runContext, pageId, pushContent)
+
+				Journal(targetConfig, JournalEntry{
+					Action:     "ReplacePage",
+					PageId:     pageId,
+					BeforeHash: HashContent(strings.TrimSpace(before.PageBody)),
+					AfterHash:  HashContent(pushContent),
+					ResultCode: ResultCode(pageErr),
+				})
+
+				if pageErr != nil {
+					return
+				}
+
+				filemanager.SaveFile(workspace.Path("pages", "target", file.Name()), content)
 			}
 		}()
 
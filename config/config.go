@@ -7,9 +7,14 @@ import (
 )
 
 type AppConfig struct {
-	Id    string `json:"id"`
-	Token string `json:"token"`
-	Realm string `json:"realm"`
+	Id        string            `json:"id"`
+	Token     string            `json:"token"`
+	AppToken  string            `json:"appToken,omitempty"`
+	Auth      string            `json:"auth,omitempty"`
+	Ticket    string            `json:"ticket,omitempty"`
+	Realm     string            `json:"realm"`
+	Secrets   map[string]string `json:"secrets,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
 }
 
 type SourceTargetConfig struct {
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"app-configuration/api"
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Context of every API call, cancelled on interrupt or once the overall timeout has passed
+var runContext = context.Background()
+
+// Deadline of each API call, set from the request-timeout flag
+var requestTimeout = api.DEFAULT_REQUEST_TIMEOUT
+
+var stopTimeout context.CancelFunc = func() {}
+
+var timeoutFlag = &cli.DurationFlag{
+	Name:  "timeout",
+	Usage: "Cancel the command once it has run this long, e.g. 30m (default no limit)",
+}
+
+var requestTimeoutFlag = &cli.DurationFlag{
+	Name:  "request-timeout",
+	Value: api.DEFAULT_REQUEST_TIMEOUT,
+	Usage: "Cancel a single Quickbase request which takes longer than this",
+}
+
+// Cancels the context on the first SIGINT or SIGTERM so requests in flight stop, a second signal exits immediately
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-interrupt:
+			log.Println(warningStyle.Render("Interrupted, cancelling requests... press Ctrl+C again to quit"))
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+
+		<-interrupt
+		os.Exit(130)
+	}()
+
+	return ctx, func() {
+		signal.Stop(interrupt)
+		cancel()
+	}
+}
+
+// Applies the timeout flags to the context the app runs with
+func StartContext(ctx *cli.Context) error {
+	runContext = ctx.Context
+	requestTimeout = ctx.Duration(requestTimeoutFlag.Name)
+
+	if timeout := ctx.Duration(timeoutFlag.Name); timeout > 0 {
+		runContext, stopTimeout = context.WithTimeout(runContext, timeout)
+	}
+
+	return nil
+}
+
+func StopContext(ctx *cli.Context) error {
+	stopTimeout()
+
+	return nil
+}
+
+// Status of a run, cancelled when the context ended before the command finished and failed when it returned an error
+func runStatus() string {
+	switch runContext.Err() {
+	case context.Canceled:
+		return "cancelled"
+	case context.DeadlineExceeded:
+		return "timed out"
+	}
+
+	if runErr != nil {
+		return "failed"
+	}
+
+	return "completed"
+}
@@ -1,82 +1,150 @@
 package main
 
 import (
+	"app-configuration/api"
 	filemanager "app-configuration/file_manager"
 	"log"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
-func CustomRules() {
-	log.Println(boldLogStyle.Render("Processing Custom Text Rules..."))
+var illegalVariableChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
 
-	content := filemanager.ReadTextFile("placeholders/custom_text.txt")
-	header := filemanager.ReadTextFile("placeholders/custom_text_header.txt")
+// Converts a field label into a legal formula variable name
+func VariableName(label string) string {
+	name := illegalVariableChars.ReplaceAllString(label, "")
 
-	fileContent := filemanager.ReadTextFile("placeholders/custom_file.txt")
+	if name == "" {
+		name = "Field"
+	}
 
-	ClearFolder("rules")
+	if name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
 
-	targetFields := GetTextFields()
-	fileFields := GetFileFields()
+	return name
+}
 
-	for _, target := range targetFields {
-		fieldsList := ""
-		fileName := "rules/" + target.TableName + ".txt"
+// Converts labels into legal variable names, suffixing _2, _3 and so on to labels which clean up to a name already used
+func uniqueVariableNames(labels []string) []string {
+	names := make([]string, len(labels))
+	used := make(map[string]bool)
 
-		if len(target.Fields) == 0 {
-			continue
+	for index, label := range labels {
+		base := VariableName(label)
+		name := base
+
+		for suffix := 2; used[strings.ToLower(name)]; suffix++ {
+			name = base + "_" + strconv.Itoa(suffix)
 		}
 
-		file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE, 0600)
+		used[strings.ToLower(name)] = true
+		names[index] = name
+	}
 
-		if err != nil {
-			log.Fatal(boldErrorStyle.Render(err.Error()))
-		}
+	return names
+}
 
-		defer file.Close()
+// Assigns a unique variable name to every field of a table, keyed by field id
+func VariableNames(fields []api.Field) map[int]string {
+	ids := make([]int, 0)
+	labels := make([]string, 0)
+	seen := make(map[int]bool)
 
-		if err := os.Truncate(fileName, 0); err != nil {
-			log.Fatal(boldErrorStyle.Render(err.Error()))
+	for _, field := range fields {
+		if seen[field.ID] {
+			continue
 		}
 
-		file.WriteString(header)
-		file.WriteString("\n\n")
+		seen[field.ID] = true
+		ids = append(ids, field.ID)
+		labels = append(labels, field.Label)
+	}
 
-		for _, field := range target.Fields {
-			fieldName := strings.ReplaceAll(field.Label, " ", "")
+	names := make(map[int]string)
 
-			newContent := strings.ReplaceAll(content, "[ABCDName]", "["+field.Label+"]")
-			newContent = strings.ReplaceAll(newContent, "ABCDVarName", fieldName)
-			newContent = strings.ReplaceAll(newContent, "ABCDMessageName", field.Label)
+	for index, name := range uniqueVariableNames(labels) {
+		names[ids[index]] = name
+	}
 
-			file.WriteString(newContent)
-			file.WriteString("\n\n")
+	return names
+}
 
-			fieldsList += "$" + fieldName + ", "
-		}
+func writeLegend(file *os.File, fields []api.Field, names map[int]string) {
+	file.WriteString("// Variables\n")
 
-		file.WriteString(fieldsList)
-		file.WriteString("\n\n")
+	for _, field := range fields {
+		file.WriteString("// " + field.Label + " -> $" + names[field.ID] + "\n")
 	}
 
-	for _, target := range fileFields {
-		fileName := "rules/" + target.TableName + ".txt"
+	file.WriteString("\n")
+}
+
+func CustomRules(schema []TargetField) {
+	log.Println(boldLogStyle.Render("Processing Custom Text Rules..."))
 
-		if len(target.Fields) == 0 {
+	content := filemanager.ReadTextFile(workspace.BasePath("placeholders", "custom_text.txt"))
+	header := filemanager.ReadTextFile(workspace.BasePath("placeholders", "custom_text_header.txt"))
+
+	fileContent := filemanager.ReadTextFile(workspace.BasePath("placeholders", "custom_file.txt"))
+
+	targetFields := GetTextFields(schema)
+	fileFields := make(map[string][]api.Field)
+
+	for _, target := range GetFileFields(schema) {
+		fileFields[target.TableId] = target.Fields
+	}
+
+	runProgress.Start(PHASE_RULES, len(targetFields))
+
+	for _, target := range targetFields {
+		fieldsList := ""
+		fileName := workspace.Path("rules", target.TableName+".txt")
+		tableFileFields := fileFields[target.TableId]
+
+		if len(target.Fields) == 0 && len(tableFileFields) == 0 {
+			runProgress.Done(PHASE_RULES, target.TableName, nil)
 			continue
 		}
 
-		file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE, 0600)
+		runProgress.Begin(PHASE_RULES, target.TableName)
+
+		allFields := append(append([]api.Field{}, target.Fields...), tableFileFields...)
+		names := VariableNames(allFields)
+
+		file, err := os.Create(fileName)
 
 		if err != nil {
 			log.Fatal(boldErrorStyle.Render(err.Error()))
 		}
 
-		defer file.Close()
+		writeLegend(file, allFields, names)
 
-		for _, field := range target.Fields {
-			fieldName := strings.ReplaceAll(field.Label, " ", "")
+		if len(target.Fields) > 0 {
+			file.WriteString(header)
+			file.WriteString("\n\n")
+
+			for _, field := range target.Fields {
+				fieldName := names[field.ID]
+
+				newContent := strings.ReplaceAll(content, "[ABCDName]", "["+field.Label+"]")
+				newContent = strings.ReplaceAll(newContent, "ABCDVarName", fieldName)
+				newContent = strings.ReplaceAll(newContent, "ABCDMessageName", field.Label)
+
+				file.WriteString(newContent)
+				file.WriteString("\n\n")
+
+				fieldsList += "$" + fieldName + ", "
+			}
+
+			file.WriteString(fieldsList)
+			file.WriteString("\n\n")
+		}
+
+		for _, field := range tableFileFields {
+			fieldName := names[field.ID]
 
 			newFileContent := strings.ReplaceAll(fileContent, "[ABCDName]", "["+field.Label+"]")
 			newFileContent = strings.ReplaceAll(newFileContent, "ABCDVarName", fieldName)
@@ -84,5 +152,9 @@ func CustomRules() {
 			file.WriteString(newFileContent)
 			file.WriteString("\n\n")
 		}
+
+		file.Close()
+
+		runProgress.Done(PHASE_RULES, target.TableName, nil)
 	}
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"app-configuration/api"
+	"reflect"
+	"testing"
+)
+
+func TestVariableNames(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []api.Field
+		want   map[int]string
+	}{
+		{
+			name:   "label without illegal characters",
+			fields: []api.Field{{ID: 6, Label: "Status"}},
+			want:   map[int]string{6: "Status"},
+		},
+		{
+			name:   "spaces and punctuation are removed",
+			fields: []api.Field{{ID: 7, Label: "Due Date (UTC)"}, {ID: 8, Label: "Cost - $"}},
+			want:   map[int]string{7: "DueDateUTC", 8: "Cost"},
+		},
+		{
+			name:   "leading digit is prefixed",
+			fields: []api.Field{{ID: 9, Label: "2nd Approver"}},
+			want:   map[int]string{9: "_2ndApprover"},
+		},
+		{
+			name:   "label without legal characters",
+			fields: []api.Field{{ID: 10, Label: "#"}, {ID: 11, Label: "%"}},
+			want:   map[int]string{10: "Field", 11: "Field_2"},
+		},
+		{
+			name:   "labels which sanitise to the same name",
+			fields: []api.Field{{ID: 12, Label: "Total"}, {ID: 13, Label: "Total!"}, {ID: 14, Label: "To tal"}},
+			want:   map[int]string{12: "Total", 13: "Total_2", 14: "Total_3"},
+		},
+		{
+			name:   "names differing only in case",
+			fields: []api.Field{{ID: 15, Label: "Name"}, {ID: 16, Label: "name"}},
+			want:   map[int]string{15: "Name", 16: "name_2"},
+		},
+		{
+			name:   "suffixed name taken by another label",
+			fields: []api.Field{{ID: 17, Label: "Total_2"}, {ID: 18, Label: "Total"}, {ID: 19, Label: "Total"}},
+			want:   map[int]string{17: "Total_2", 18: "Total", 19: "Total_3"},
+		},
+		{
+			name:   "repeated field keeps its first name",
+			fields: []api.Field{{ID: 20, Label: "Owner"}, {ID: 20, Label: "Owner"}, {ID: 21, Label: "Owner"}},
+			want:   map[int]string{20: "Owner", 21: "Owner_2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := VariableNames(test.fields); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("VariableNames() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
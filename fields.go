@@ -3,6 +3,7 @@ package main
 import (
 	"app-configuration/api"
 	filemanager "app-configuration/file_manager"
+	"errors"
 	"log"
 	"os"
 	"strconv"
@@ -11,38 +12,69 @@ import (
 )
 
 type TargetField struct {
-	TableId   string
-	TableName string
-	Fields    []api.Field
+	TableId    string
+	TableName  string
+	TableAlias string
+	Fields     []api.Field
 }
 
-func ProcessSourceFields(sourceConfig api.Quickbase) {
+func ProcessSourceFields(sourceConfig api.Quickbase, targetConfig api.Quickbase) {
 	log.Println(boldLogStyle.Render("Processing source fields"))
 
 	var wg sync.WaitGroup
-	mapping := filemanager.ReadMapping()
+	mapping := filemanager.ReadMapping(workspace.Path("mapping", "mapping.json"))
+	scopedTables := filemanager.ReadJSONFile[[]string](workspace.Path("mapping", SCOPE_FILE+".json"))
+	reports := ReadReportMapping()
+	aliases := sourceTableAliases(sourceConfig)
+
+	runProgress.Start(PHASE_FIELD_SCAN, len(scopedTables))
+
+	// Loop through source table ids in scope
+	for _, tableId := range scopedTables {
+		if runContext.Err() != nil {
+			break
+		}
 
-	// Loop through source table ids
-	for tableId := range mapping {
 		wg.Add(1)
 
 		go func() {
 			defer wg.Done()
+			var scanErr error
+
+			defer func() {
+				runProgress.Done(PHASE_FIELD_SCAN, tableId, scanErr)
+			}()
+
+			runProgress.Begin(PHASE_FIELD_SCAN, tableId)
+
+			fields, scanErr := sourceConfig.GetFields(runContext, tableId)
+
+			if scanErr != nil {
+				return
+			}
 
-			fields := sourceConfig.GetFields(tableId)
 			fieldsToUpdate := make([]api.Field, 0)
 
-			// Find only formula fields where table id exists
+			// Find only formula fields where table id or a secret exists
 			for _, field := range fields {
-				formula := field.Properties.Formula
+				formula := ProtectSecrets(field.Properties.Formula, sourceConfig, "Field "+field.Label)
+				field.Properties.Formula = formula
 				flag := false
 
-				if len(formula) > 0 {
+				if len(formula) > 0 && runScope.IncludesField(field) {
+					if HasSecretPlaceholders(formula) {
+						flag = ResolveSecrets(formula, sourceConfig, "Field "+field.Label) != ResolveSecrets(formula, targetConfig, "Field "+field.Label)
+					}
+
 					for source := range mapping {
 						if strings.Contains(formula, source) {
 							flag = true
 						}
 					}
+
+					if RewriteReportIds(formula, tableId, reports, aliases) != formula {
+						flag = true
+					}
 				}
 
 				if flag {
@@ -53,7 +85,7 @@ func ProcessSourceFields(sourceConfig api.Quickbase) {
 			}
 
 			if len(fieldsToUpdate) > 0 {
-				filemanager.SaveJsonToFile("fields/source/"+tableId, fieldsToUpdate)
+				filemanager.SaveJsonToFile(workspace.Path("fields", "source", tableId), fieldsToUpdate)
 			}
 		}()
 	}
@@ -61,83 +93,123 @@ func ProcessSourceFields(sourceConfig api.Quickbase) {
 	wg.Wait()
 }
 
-func SaveFields(targetConfig api.Quickbase) {
+func SaveFields(sourceConfig api.Quickbase, targetConfig api.Quickbase) {
 	var wg sync.WaitGroup
-	mapping := filemanager.ReadMapping()
-	files, err := os.ReadDir("fields/source")
+	mapping := filemanager.ReadMapping(workspace.Path("mapping", "mapping.json"))
+	reports := ReadReportMapping()
+	aliases := sourceTableAliases(sourceConfig)
+	files, err := os.ReadDir(workspace.Path("fields", "source"))
 
 	if err != nil {
 		log.Fatal(errorStyle.Render(err.Error()))
 	}
 
+	sourceFields := make(map[string][]api.Field)
+	total := 0
+
+	for _, file := range files {
+		sourceFields[file.Name()] = filemanager.ReadFields(workspace.Path("fields", "source", file.Name()))
+		total += len(sourceFields[file.Name()])
+	}
+
+	runProgress.Start(PHASE_FIELD_UPDATE, total)
+
 	for _, file := range files {
-		fields := filemanager.ReadFields("fields/source/" + file.Name())
-		targetTable := mapping[strings.TrimSuffix(file.Name(), ".json")]
+		if runContext.Err() != nil {
+			break
+		}
+
+		fields := sourceFields[file.Name()]
+		sourceTable := strings.TrimSuffix(file.Name(), ".json")
+		targetTable := mapping[sourceTable]
+		currentFormulas := make(map[int]string)
+
+		targetFields, err := targetConfig.GetFields(runContext, targetTable)
+
+		// The fields of a table whose current formulas cannot be read are failed rather than blindly overwritten
+		if err != nil {
+			for _, field := range fields {
+				if runScope.IncludesField(field) {
+					runProgress.Done(PHASE_FIELD_UPDATE, targetTable+"."+field.Label, err)
+				}
+			}
+
+			continue
+		}
+
+		for _, targetField := range targetFields {
+			currentFormulas[targetField.ID] = targetField.Properties.Formula
+		}
 
 		for _, field := range fields {
+			if runContext.Err() != nil {
+				break
+			}
+
+			if !runScope.IncludesField(field) {
+				continue
+			}
+
 			wg.Add(1)
 
 			go func() {
 				defer wg.Done()
-				formula := field.Properties.Formula
+				var fieldErr error
+
+				defer func() {
+					runProgress.Done(PHASE_FIELD_UPDATE, targetTable+"."+field.Label, fieldErr)
+				}()
+
+				runProgress.Begin(PHASE_FIELD_UPDATE, targetTable+"."+field.Label)
+				formula := RewriteReportIds(field.Properties.Formula, sourceTable, reports, aliases)
 
 				for source, target := range mapping {
 					formula = strings.ReplaceAll(formula, source, target)
 				}
 
 				field.Properties.Formula = formula
+				pushFormula := ResolveSecrets(formula, targetConfig, "Field "+field.Label)
 
 				log.Println(logStyle.Render("Updating Field -- " + field.Label))
 
-				targetConfig.UpdateField(targetTable, strconv.Itoa(field.ID), formula)
-				filemanager.SaveJsonToFile("fields/target/"+targetTable+"_"+strconv.Itoa(field.ID)+"_"+field.Label, field)
-			}()
-		}
-	}
-
-	wg.Wait()
-}
-
-func SaveTargetFields(targetConfig api.Quickbase) []TargetField {
-	var wg sync.WaitGroup
-
-	log.Println(boldLogStyle.Render("Saving Target Fields..."))
-
-	if _, err := os.Stat("target_fields.json"); err == nil {
-		log.Println(warningStyle.Render("target_fields.json already exists, using it"))
-		return filemanager.ReadJSONFile[[]TargetField]("target_fields.json")
-	}
+				_, fieldErr = targetConfig.UpdateField(runContext, targetTable, strconv.Itoa(field.ID), pushFormula)
 
-	tablesRes := targetConfig.GetTables()
+				Journal(targetConfig, JournalEntry{
+					Action:     "UpdateField",
+					TableId:    targetTable,
+					FieldId:    strconv.Itoa(field.ID),
+					BeforeHash: HashContent(currentFormulas[field.ID]),
+					AfterHash:  HashContent(pushFormula),
+					ResultCode: ResultCode(fieldErr),
+				})
 
-	targetFields := make([]TargetField, len(tablesRes.Tables))
-
-	for index, table := range tablesRes.Tables {
-		wg.Add(1)
-
-		go func(i int, t api.Table) {
-			defer wg.Done()
+				if fieldErr != nil {
+					return
+				}
 
-			fields := targetConfig.GetFields(t.ID)
+				fileName := filemanager.SanitizeFileName(targetTable + "_" + strconv.Itoa(field.ID) + "_" + field.Label)
 
-			targetFields[index] = TargetField{
-				TableId:   t.ID,
-				TableName: filemanager.SanitizeFileName(t.Name),
-				Fields:    fields,
-			}
-
-		}(index, table)
+				if err := filemanager.SaveJsonToFile(workspace.Path("fields", "target", fileName), field); err != nil {
+					log.Println(errorStyle.Render("Failed to save Field -- " + field.Label + " -- " + err.Error()))
+				}
+			}()
+		}
 	}
 
 	wg.Wait()
 
-	filemanager.SaveJsonToFile("target_fields", targetFields)
+	// The cached target schema holds the formulas from before the update
+	InvalidateSchemaCache(targetConfig)
+}
 
-	return targetFields
+func SaveTargetFields(targetConfig api.Quickbase, options SchemaCacheOptions) ([]TargetField, error) {
+	log.Println(boldLogStyle.Render("Saving Target Fields..."))
+
+	return LoadSchema(targetConfig, options)
 }
 
-func GetTextFields() []TargetField {
-	targetFields := filemanager.ReadJSONFile[[]TargetField]("target_fields.json")
+func GetTextFields(schema []TargetField) []TargetField {
+	targetFields := append([]TargetField{}, schema...)
 
 	for index, target := range targetFields {
 		textFields := make([]api.Field, 0)
@@ -154,8 +226,8 @@ func GetTextFields() []TargetField {
 	return targetFields
 }
 
-func GetToUpdateTextFields() []TargetField {
-	targetFields := GetTextFields()
+func GetToUpdateTextFields(schema []TargetField) []TargetField {
+	targetFields := GetTextFields(schema)
 
 	for index, target := range targetFields {
 		textFields := make([]api.Field, 0)
@@ -172,8 +244,8 @@ func GetToUpdateTextFields() []TargetField {
 	return targetFields
 }
 
-func GetFileFields() []TargetField {
-	targetFields := filemanager.ReadJSONFile[[]TargetField]("target_fields.json")
+func GetFileFields(schema []TargetField) []TargetField {
+	targetFields := append([]TargetField{}, schema...)
 
 	for index, target := range targetFields {
 		fileFields := make([]api.Field, 0)
@@ -190,23 +262,40 @@ func GetFileFields() []TargetField {
 	return targetFields
 }
 
-func VerifyFieldsLength(targetConfig api.Quickbase) {
+func VerifyFieldsLength(targetConfig api.Quickbase, options SchemaCacheOptions) error {
 	log.Println(boldLogStyle.Render("Verifying Fields Length"))
 
 	count := 0
 
-	SaveTargetFields(targetConfig)
+	schema, err := SaveTargetFields(targetConfig, options)
 
-	targetFields := GetTextFields()
+	if err != nil {
+		return err
+	}
+
+	targetFields := runScope.FilterSchema(GetTextFields(schema))
+	total := 0
+
+	for _, target := range targetFields {
+		total += len(target.Fields)
+	}
+
+	runProgress.Start(PHASE_FIELD_VERIFY, total)
 
 	for _, target := range targetFields {
 		for _, field := range target.Fields {
+			item := target.TableName + "." + field.Label
+
 			if (field.FieldType == TEXT_FIELD && field.Properties.MaxLength != TEXT_MAX_LENGTH) || (field.FieldType == MULTILINE_FIELD && field.Properties.MaxLength != MULTILINE_MAX_LENGTH) {
-				log.Println(errorStyle.Render("Max Length Not Updated For Field " + field.Label + " in Table " + target.TableName))
+				runProgress.Done(PHASE_FIELD_VERIFY, item, errors.New("Max Length Not Updated For Field "+field.Label+" in Table "+target.TableName))
 				count += 1
+			} else {
+				runProgress.Done(PHASE_FIELD_VERIFY, item, nil)
 			}
 		}
 	}
 
 	log.Println(boldLogStyle.Render("Verified fields successfully"))
+
+	return nil
 }
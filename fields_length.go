@@ -2,8 +2,10 @@ package main
 
 import (
 	"app-configuration/api"
+	"errors"
 	"log"
 	"os"
+	"strconv"
 	"sync"
 )
 
@@ -15,12 +17,12 @@ var (
 	MULTILINE_MAX_LENGTH = 200
 )
 
-func UpdateFieldsLength(targetConfig api.Quickbase) {
+func UpdateFieldsLength(targetConfig api.Quickbase, schema []TargetField) {
 	var wg sync.WaitGroup
 
-	textFields := GetToUpdateTextFields()
+	textFields := runScope.FilterSchema(GetToUpdateTextFields(schema))
 
-	file, err := os.OpenFile("fields.txt", os.O_APPEND|os.O_CREATE, 0600)
+	file, err := os.Create(workspace.Path("fields.txt"))
 
 	if err != nil {
 		log.Fatal(boldErrorStyle.Render(err.Error()))
@@ -28,28 +30,56 @@ func UpdateFieldsLength(targetConfig api.Quickbase) {
 
 	defer file.Close()
 
-	if err := os.Truncate("fields.txt", 0); err != nil {
-		log.Fatal(boldErrorStyle.Render(err.Error()))
+	total := 0
+
+	for _, target := range textFields {
+		total += len(target.Fields)
 	}
 
+	runProgress.Start(PHASE_FIELD_LENGTH, total)
+
 	for _, target := range textFields {
 		if len(target.Fields) == 0 {
 			continue
 		}
 
 		for _, field := range target.Fields {
+			if runContext.Err() != nil {
+				break
+			}
+
 			wg.Add(1)
 
 			go func() {
 				defer wg.Done()
 
-				targetConfig.UpdateFieldLength(target.TableId, field.ID, field.FieldType)
+				item := target.TableName + "." + field.Label
+				runProgress.Begin(PHASE_FIELD_LENGTH, item)
+
+				res, err := targetConfig.UpdateFieldLength(runContext, target.TableId, field.ID, field.FieldType)
+
+				if err == nil && res.ID != field.ID {
+					err = errors.New("field was not updated")
+				}
+
+				Journal(targetConfig, JournalEntry{
+					Action:     "UpdateFieldLength",
+					TableId:    target.TableId,
+					FieldId:    strconv.Itoa(field.ID),
+					BeforeHash: HashContent(strconv.Itoa(field.Properties.MaxLength)),
+					AfterHash:  HashContent(strconv.Itoa(res.Properties.MaxLength)),
+					ResultCode: ResultCode(err),
+				})
+
+				runProgress.Done(PHASE_FIELD_LENGTH, item, err)
 			}()
 		}
 	}
 
 	wg.Wait()
 
+	InvalidateSchemaCache(targetConfig)
+
 	for _, target := range textFields {
 		file.WriteString(target.TableName + "\n")
 		file.WriteString("--------------\n")
@@ -6,21 +6,35 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 )
 
+// Replaces the characters which cannot be used in a file name, a name made of dots only would point at a folder
 func SanitizeFileName(fileName string) string {
-	regex, err := regexp.Compile(`[?!&*/]`)
+	regex, err := regexp.Compile(`[?!&*/\\:]`)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	return regex.ReplaceAllString(fileName, "_")
+	name := regex.ReplaceAllString(fileName, "_")
+
+	if name != "" && strings.Trim(name, ".") == "" {
+		return strings.Repeat("_", len(name))
+	}
+
+	return name
 }
 
-func ReadMapping() map[string]string {
-	file, err := os.Open("mapping/mapping.json")
+// Sanitizes only the last element of a path, keeping its folders intact
+func SanitizeFilePath(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), SanitizeFileName(filepath.Base(filePath)))
+}
+
+func ReadMapping(filePath string) map[string]string {
+	file, err := os.Open(filePath)
 
 	if err != nil {
 		log.Fatal(err)
@@ -52,10 +66,10 @@ func ReadJSONFile[T any](filePath string) T {
 }
 
 func SaveJsonToFile(fileName string, content any) error {
-	file, err := os.Create(SanitizeFileName(fileName) + ".json")
+	file, err := os.Create(SanitizeFilePath(fileName) + ".json")
 
 	if err != nil {
-		return nil
+		return err
 	}
 
 	defer file.Close()
@@ -97,7 +111,7 @@ func ReadTextFile(filePath string) string {
 }
 
 func SaveFile(fileName string, content string) {
-	err := os.WriteFile(SanitizeFileName(fileName), []byte(content), 0644)
+	err := os.WriteFile(SanitizeFilePath(fileName), []byte(content), 0644)
 
 	if err != nil {
 		log.Fatal(err)
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	JOURNAL_FILENAME  = "journal.jsonl"
+	MANIFEST_FILENAME = "manifest"
+)
+
+// JournalEntry records a single write made to a Quickbase app
+type JournalEntry struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Run         string    `json:"run"`
+	Environment string    `json:"environment"`
+	Realm       string    `json:"realm"`
+	AppId       string    `json:"appId"`
+	Action      string    `json:"action"`
+	TableId     string    `json:"tableId,omitempty"`
+	FieldId     string    `json:"fieldId,omitempty"`
+	PageId      string    `json:"pageId,omitempty"`
+	Variable    string    `json:"variable,omitempty"`
+	BeforeHash  string    `json:"beforeHash"`
+	AfterHash   string    `json:"afterHash"`
+	ResultCode  string    `json:"resultCode"`
+	Operator    string    `json:"operator"`
+}
+
+// RunManifest describes a single invocation and is saved in its workspace
+type RunManifest struct {
+	Command  string    `json:"command"`
+	Args     []string  `json:"args"`
+	Operator string    `json:"operator"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Writes   int       `json:"writes"`
+}
+
+type JournalFilter struct {
+	AppId   string
+	TableId string
+	Since   time.Time
+	Until   time.Time
+}
+
+var (
+	journalMutex sync.Mutex
+	manifest     RunManifest
+	operator     string
+	// Error returned by the command, recorded before the After hooks finish the run
+	runErr error
+)
+
+var operatorFlag = &cli.StringFlag{
+	Name:  "operator",
+	Usage: "Name recorded in the journal for writes made by this run (defaults to the OS user)",
+}
+
+func GetOperator(ctx *cli.Context) string {
+	if name := ctx.String("operator"); name != "" {
+		return name
+	}
+
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+
+	return "unknown"
+}
+
+// Result code journaled for a write, the errcode or HTTP status of a failed call
+func ResultCode(err error) string {
+	var xmlErr *api.XMLError
+	var restErr *api.RESTError
+
+	switch {
+	case err == nil:
+		return "0"
+	case errors.As(err, &xmlErr):
+		return xmlErr.Code
+	case errors.As(err, &restErr):
+		return strconv.Itoa(restErr.Status)
+	}
+
+	return "error"
+}
+
+func HashContent(content string) string {
+	hash := sha256.Sum256([]byte(content))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// Appends an entry to the journal in the base directory, shared by all runs
+func Journal(config api.Quickbase, entry JournalEntry) {
+	// Replayed writes never reached Quickbase and do not belong in its history
+	if _, ok := runCassette.(*api.Replayer); ok {
+		return
+	}
+
+	entry.Timestamp = time.Now()
+	entry.Run = workspace.Name()
+	entry.Realm = config.Realm
+	entry.AppId = config.AppId
+	entry.Operator = operator
+
+	if entry.Environment == "" {
+		entry.Environment = "target"
+	}
+
+	line, err := json.Marshal(entry)
+
+	if err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	journalMutex.Lock()
+	defer journalMutex.Unlock()
+
+	file, err := os.OpenFile(workspace.BasePath(JOURNAL_FILENAME), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	manifest.Writes += 1
+}
+
+func ReadJournal(base string, filter JournalFilter) []JournalEntry {
+	entries := make([]JournalEntry, 0)
+
+	file, err := os.Open(Workspace{Base: base}.BasePath(JOURNAL_FILENAME))
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries
+		}
+
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var entry JournalEntry
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			log.Println(warningStyle.Render("Skipping invalid journal entry -- " + err.Error()))
+			continue
+		}
+
+		if filter.AppId != "" && entry.AppId != filter.AppId {
+			continue
+		}
+
+		if filter.TableId != "" && entry.TableId != filter.TableId {
+			continue
+		}
+
+		if !filter.Since.IsZero() && entry.Timestamp.Before(filter.Since) {
+			continue
+		}
+
+		if !filter.Until.IsZero() && !entry.Timestamp.Before(filter.Until) {
+			continue
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	return entries
+}
+
+func saveManifest() {
+	if err := filemanager.SaveJsonToFile(workspace.Path(MANIFEST_FILENAME), manifest); err != nil {
+		log.Println(errorStyle.Render(err.Error()))
+	}
+}
+
+// Starts a run workspace and records its manifest
+func StartRun(ctx *cli.Context) error {
+	if err := StartWorkspace(ctx); err != nil {
+		return err
+	}
+
+	operator = GetOperator(ctx)
+	runScope = GetScope(ctx)
+
+	if IsJSONOutput(ctx) {
+		runReport = NewReport(ctx)
+		runProgress = reportProgress{report: runReport, next: logProgress{}}
+	}
+
+	manifest = RunManifest{
+		Command:  ctx.Command.FullName(),
+		Args:     os.Args[1:],
+		Operator: operator,
+		Started:  time.Now(),
+		Status:   "running",
+	}
+
+	saveManifest()
+
+	return nil
+}
+
+// Keeps the error of a command for FinishRun, main exits with it once the run is finished
+func RecordRunError(ctx *cli.Context, err error) {
+	if err != nil {
+		runErr = err
+	}
+}
+
+func FinishRun(ctx *cli.Context) error {
+	manifest.Finished = time.Now()
+	manifest.Status = runStatus()
+
+	if runErr != nil {
+		manifest.Error = runErr.Error()
+	}
+
+	saveManifest()
+
+	if runReport != nil {
+		runReport.Finish(manifest.Status, runErr)
+
+		return runReport.Write()
+	}
+
+	return nil
+}
+
+func parseDateFlag(ctx *cli.Context, name string) (time.Time, error) {
+	value := strings.TrimSpace(ctx.String(name))
+
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if date, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return date, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
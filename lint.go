@@ -0,0 +1,391 @@
+package main
+
+import (
+	"app-configuration/api"
+	"app-configuration/config"
+	filemanager "app-configuration/file_manager"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	LINT_MAPPED   = "mapped"
+	LINT_UNMAPPED = "unmapped"
+	LINT_EXTERNAL = "external"
+
+	LINT_DBID      = "dbid"
+	LINT_REALM     = "realm"
+	LINT_USERTOKEN = "usertoken"
+	LINT_APPTOKEN  = "apptoken"
+	LINT_FIELD_ID  = "fieldid"
+)
+
+var (
+	dbidRegex        = regexp.MustCompile(`\bb[a-z0-9]{8}\b`)
+	dbidContextRegex = regexp.MustCompile(`(?i)(/db/|dbid\s*=\s*['"]?|_dbid_)$`)
+	realmRegex       = regexp.MustCompile(`\b[a-z0-9][a-z0-9-]*\.quickbase\.com\b`)
+	tokenRegex       = regexp.MustCompile(`(?i)\b(usertoken|apptoken)\s*=\s*([A-Za-z0-9_]+)`)
+	tokenHeaderRegex = regexp.MustCompile(`QB-USER-TOKEN\s+([A-Za-z0-9_]+)`)
+	queryFieldRegex  = regexp.MustCompile(`\{\s*'?(\d+)'?\s*\.\s*(?:X?EX|X?CT|X?HAS|O?BF|O?AF|X?IR|X?SW|LTE?|GTE?|X?TV)\s*\.`)
+	fieldListRegex   = regexp.MustCompile(`(?i)\b(?:clist|slist|fid)\s*=\s*['"]?([\d.]+)`)
+)
+
+// LintFinding is a hard-coded identifier found in a formula or code page
+type LintFinding struct {
+	Source string `json:"source"`
+	Line   int    `json:"line"`
+	Kind   string `json:"kind"`
+	Value  string `json:"value"`
+	Class  string `json:"class"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type lintContext struct {
+	sourceConfig api.Quickbase
+	targetConfig api.Quickbase
+	mapping      map[string]string
+	sourceTables map[string]api.Table
+	targetTables map[string]api.Table
+	sourceFields map[string]map[int]api.Field
+	targetFields map[string]map[int]api.Field
+}
+
+func lineOf(text string, index int) int {
+	return strings.Count(text[:index], "\n") + 1
+}
+
+func (c lintContext) classifyDBID(dbid string) (string, string) {
+	if target, ok := c.mapping[dbid]; ok {
+		return LINT_MAPPED, "-> " + target
+	}
+
+	if table, ok := c.sourceTables[dbid]; ok {
+		return LINT_UNMAPPED, "source table " + table.Name + " has no target table"
+	}
+
+	if table, ok := c.targetTables[dbid]; ok {
+		return LINT_EXTERNAL, "already points to target table " + table.Name
+	}
+
+	if dbid == c.targetConfig.AppId {
+		return LINT_EXTERNAL, "already points to the target app"
+	}
+
+	return LINT_EXTERNAL, "not part of the source app"
+}
+
+func (c lintContext) classifyRealm(realm string) (string, string) {
+	if realm == c.sourceConfig.Realm {
+		if target, ok := c.mapping[realm]; ok {
+			return LINT_MAPPED, "-> " + target
+		}
+
+		return LINT_MAPPED, "same realm as the target"
+	}
+
+	if realm == c.targetConfig.Realm {
+		return LINT_EXTERNAL, "already points to the target realm"
+	}
+
+	return LINT_EXTERNAL, "realm of another environment"
+}
+
+func (c lintContext) classifyToken(token string) (string, string) {
+	for _, secret := range knownSecrets() {
+		if secret.value == token {
+			return LINT_MAPPED, "replaced by the " + secret.name + " secret of app " + secret.appId
+		}
+	}
+
+	return LINT_UNMAPPED, "hard-coded token which is not a configured secret"
+}
+
+func (c lintContext) classifyField(tableId string, fieldId int) (string, string) {
+	sourceFields, ok := c.sourceFields[tableId]
+
+	if !ok {
+		return LINT_EXTERNAL, "table of the field is unknown"
+	}
+
+	sourceField, ok := sourceFields[fieldId]
+
+	if !ok {
+		return LINT_UNMAPPED, "field does not exist in source table " + c.sourceTables[tableId].Name
+	}
+
+	targetFields, ok := c.targetFields[c.mapping[tableId]]
+
+	if !ok {
+		return LINT_UNMAPPED, "source table " + c.sourceTables[tableId].Name + " has no target table"
+	}
+
+	targetField, ok := targetFields[fieldId]
+
+	if !ok {
+		return LINT_UNMAPPED, "field " + sourceField.Label + " does not exist in the target table"
+	}
+
+	if targetField.Label != sourceField.Label {
+		return LINT_UNMAPPED, "field " + sourceField.Label + " is " + targetField.Label + " in the target table"
+	}
+
+	return LINT_MAPPED, sourceField.Label
+}
+
+// Finds hard-coded identifiers in a formula or page, using tableId as the table of field IDs without a nearer DBID
+func (c lintContext) LintText(source string, tableId string, text string) []LintFinding {
+	findings := make([]LintFinding, 0)
+
+	// DBIDs seen so far give the table context for field IDs which follow them
+	contexts := make(map[int]string)
+
+	for _, match := range dbidRegex.FindAllStringIndex(text, -1) {
+		dbid := text[match[0]:match[1]]
+		_, known := c.mapping[dbid]
+		_, sourceTable := c.sourceTables[dbid]
+		_, targetTable := c.targetTables[dbid]
+
+		if !known && !sourceTable && !targetTable && !strings.ContainsAny(dbid, "0123456789") && !dbidContextRegex.MatchString(text[:match[0]]) {
+			continue
+		}
+
+		class, detail := c.classifyDBID(dbid)
+		contexts[match[0]] = dbid
+
+		findings = append(findings, LintFinding{Source: source, Line: lineOf(text, match[0]), Kind: LINT_DBID, Value: dbid, Class: class, Detail: detail})
+	}
+
+	for _, match := range realmRegex.FindAllStringIndex(text, -1) {
+		realm := text[match[0]:match[1]]
+
+		if realm == "api.quickbase.com" {
+			continue
+		}
+
+		class, detail := c.classifyRealm(realm)
+		findings = append(findings, LintFinding{Source: source, Line: lineOf(text, match[0]), Kind: LINT_REALM, Value: realm, Class: class, Detail: detail})
+	}
+
+	for _, match := range tokenRegex.FindAllStringSubmatchIndex(text, -1) {
+		kind := LINT_USERTOKEN
+
+		if strings.EqualFold(text[match[2]:match[3]], "apptoken") {
+			kind = LINT_APPTOKEN
+		}
+
+		class, detail := c.classifyToken(text[match[4]:match[5]])
+		findings = append(findings, LintFinding{Source: source, Line: lineOf(text, match[0]), Kind: kind, Value: RedactSecret(text[match[4]:match[5]]), Class: class, Detail: detail})
+	}
+
+	for _, match := range tokenHeaderRegex.FindAllStringSubmatchIndex(text, -1) {
+		class, detail := c.classifyToken(text[match[2]:match[3]])
+		findings = append(findings, LintFinding{Source: source, Line: lineOf(text, match[0]), Kind: LINT_USERTOKEN, Value: RedactSecret(text[match[2]:match[3]]), Class: class, Detail: detail})
+	}
+
+	fieldTable := func(index int) string {
+		nearest := -1
+		table := tableId
+
+		for position, dbid := range contexts {
+			if position < index && position > nearest {
+				nearest = position
+				table = dbid
+			}
+		}
+
+		return table
+	}
+
+	addField := func(index int, value string) {
+		fieldId, err := strconv.Atoi(value)
+
+		if err != nil {
+			return
+		}
+
+		class, detail := c.classifyField(fieldTable(index), fieldId)
+		findings = append(findings, LintFinding{Source: source, Line: lineOf(text, index), Kind: LINT_FIELD_ID, Value: value, Class: class, Detail: detail})
+	}
+
+	for _, match := range queryFieldRegex.FindAllStringSubmatchIndex(text, -1) {
+		addField(match[0], text[match[2]:match[3]])
+	}
+
+	for _, match := range fieldListRegex.FindAllStringSubmatchIndex(text, -1) {
+		for _, value := range strings.Split(text[match[2]:match[3]], ".") {
+			addField(match[0], value)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+
+	return findings
+}
+
+// Keeps the first characters of a secret so findings can be told apart without printing it
+func RedactSecret(secret string) string {
+	if len(secret) <= 6 {
+		return strings.Repeat("*", len(secret))
+	}
+
+	return secret[:6] + strings.Repeat("*", len(secret)-6)
+}
+
+// Fields of each table by ID, the errors of failed requests are joined once every table has been fetched
+func fetchFieldsByTable(config api.Quickbase, tableIds []string) (map[string]map[int]api.Field, error) {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var fetchErr error
+
+	fieldsByTable := make(map[string]map[int]api.Field)
+
+	for _, tableId := range tableIds {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			fields := make(map[int]api.Field)
+			tableFields, err := config.GetFields(runContext, tableId)
+
+			for _, field := range tableFields {
+				fields[field.ID] = field
+			}
+
+			mutex.Lock()
+			fieldsByTable[tableId] = fields
+			fetchErr = errors.Join(fetchErr, err)
+			mutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return fieldsByTable, fetchErr
+}
+
+// Lints every formula of the source app and every configured code page
+func Lint(sourceConfig api.Quickbase, targetConfig api.Quickbase, mapping map[string]string) ([]LintFinding, error) {
+	log.Println(boldLogStyle.Render("Linting formulas and code pages..."))
+
+	context := lintContext{
+		sourceConfig: sourceConfig,
+		targetConfig: targetConfig,
+		mapping:      mapping,
+		sourceTables: make(map[string]api.Table),
+		targetTables: make(map[string]api.Table),
+	}
+
+	sourceIds := make([]string, 0)
+	targetIds := make([]string, 0)
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](workspace.Path("tables", sourceConfig.AppId+".json")) {
+		if !runScope.IncludesTable(table.ID, table.Name, table.Alias) {
+			continue
+		}
+
+		context.sourceTables[table.ID] = table
+		sourceIds = append(sourceIds, table.ID)
+	}
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](workspace.Path("tables", targetConfig.AppId+".json")) {
+		context.targetTables[table.ID] = table
+		targetIds = append(targetIds, table.ID)
+	}
+
+	var err error
+
+	if context.sourceFields, err = fetchFieldsByTable(sourceConfig, sourceIds); err != nil {
+		return nil, err
+	}
+
+	if context.targetFields, err = fetchFieldsByTable(targetConfig, targetIds); err != nil {
+		return nil, err
+	}
+
+	findings := make([]LintFinding, 0)
+
+	sort.Strings(sourceIds)
+
+	for _, tableId := range sourceIds {
+		fieldIds := make([]int, 0)
+
+		for fieldId := range context.sourceFields[tableId] {
+			fieldIds = append(fieldIds, fieldId)
+		}
+
+		sort.Ints(fieldIds)
+
+		for _, fieldId := range fieldIds {
+			field := context.sourceFields[tableId][fieldId]
+
+			if field.Properties.Formula == "" || !runScope.IncludesField(field) {
+				continue
+			}
+
+			source := "formula " + context.sourceTables[tableId].Name + " / " + field.Label
+			findings = append(findings, context.LintText(source, tableId, field.Properties.Formula)...)
+		}
+	}
+
+	names, err := configuredPageNames(sourceConfig)
+
+	if err != nil {
+		return findings, err
+	}
+
+	for _, pageId := range config.ReadConfig().Pages {
+		strPageId := strconv.Itoa(pageId)
+
+		if !runScope.IncludesPage(strPageId, names[strPageId]) {
+			continue
+		}
+
+		res, err := sourceConfig.GetPage(runContext, strPageId)
+
+		if err != nil {
+			return findings, err
+		}
+
+		findings = append(findings, context.LintText("page "+strPageId, "", res.PageBody)...)
+	}
+
+	filemanager.SaveJsonToFile(workspace.Path("lint"), findings)
+
+	return findings, nil
+}
+
+func PrintLintFindings(findings []LintFinding, showMapped bool) {
+	counts := make(map[string]int)
+
+	for _, finding := range findings {
+		counts[finding.Class] += 1
+
+		if finding.Class == LINT_MAPPED && !showMapped {
+			continue
+		}
+
+		line := fmt.Sprintf("%-9s %-10s %-24s %s:%d  %s", finding.Class, finding.Kind, finding.Value, finding.Source, finding.Line, finding.Detail)
+
+		switch finding.Class {
+		case LINT_UNMAPPED:
+			line = errorStyle.Render(line)
+		case LINT_EXTERNAL:
+			line = warningStyle.Render(line)
+		default:
+			line = logStyle.Render(line)
+		}
+
+		fmt.Println(line)
+	}
+
+	log.Println(boldLogStyle.Render(fmt.Sprintf("%d mapped, %d unmapped, %d external", counts[LINT_MAPPED], counts[LINT_UNMAPPED], counts[LINT_EXTERNAL])))
+}
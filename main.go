@@ -4,10 +4,12 @@ import (
 	"app-configuration/api"
 	"app-configuration/config"
 	filemanager "app-configuration/file_manager"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path"
+	"time"
 
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/lipgloss"
@@ -21,88 +23,166 @@ var (
 	boldErrorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff0000")).Bold(true)
 	boldLogStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#008060")).Bold(true)
 
-	folders = []string{"pages", "pages/source", "pages/target", "fields", "fields/source", "fields/target", "tables", "mapping", "rules", "placeholders"}
+	folders = []string{"placeholders", RUNS_FOLDER}
 )
 
-func CreateMapping(sourceConfig api.Quickbase, targetConfig api.Quickbase) map[string]string {
+func CreateMapping(sourceConfig api.Quickbase, targetConfig api.Quickbase) (map[string]string, error) {
 	log.Println(boldLogStyle.Render("Creating mapping..."))
 
+	runProgress.Start(PHASE_MAPPING, 2)
+
 	mapping := make(map[string]string)
 
-	sourceRes := sourceConfig.GetTables()
-	targetRes := targetConfig.GetTables()
+	runProgress.Begin(PHASE_MAPPING, "source tables")
+	sourceRes, err := sourceConfig.GetTables(runContext)
+	runProgress.Done(PHASE_MAPPING, "source tables", err)
 
-	filemanager.SaveJsonToFile("tables/"+sourceRes.AppId, sourceRes.Tables)
-	filemanager.SaveJsonToFile("tables/"+targetRes.AppId, targetRes.Tables)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, sourceTable := range sourceRes.Tables {
-		for _, targetTable := range targetRes.Tables {
-			if sourceTable.Name == targetTable.Name {
-				mapping[sourceTable.ID] = targetTable.ID
-			}
+	runProgress.Begin(PHASE_MAPPING, "target tables")
+	targetRes, err := targetConfig.GetTables(runContext)
+	runProgress.Done(PHASE_MAPPING, "target tables", err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filemanager.SaveJsonToFile(workspace.Path("tables", sourceRes.AppId), sourceRes.Tables)
+	filemanager.SaveJsonToFile(workspace.Path("tables", targetRes.AppId), targetRes.Tables)
+
+	for sourceId, targetId := range MatchTables(sourceRes.Tables, targetRes.Tables) {
+		mapping[sourceId] = targetId
+	}
+
+	for sourceId, targetId := range ValidMappingOverrides(ReadMappingOverrides(sourceRes.AppId, targetRes.AppId), targetRes.Tables) {
+		if targetId == "" {
+			delete(mapping, sourceId)
+		} else {
+			mapping[sourceId] = targetId
 		}
 	}
 
-	mapping[sourceRes.AppId] = targetRes.AppId
+	scopedTables := make([]string, 0)
 
-	if sourceConfig.UserToken != targetConfig.UserToken {
-		mapping[sourceConfig.UserToken] = targetConfig.UserToken
+	for _, table := range sourceRes.Tables {
+		if _, ok := mapping[table.ID]; ok && runScope.IncludesTable(table.ID, table.Name, table.Alias) {
+			scopedTables = append(scopedTables, table.ID)
+		}
 	}
 
+	runScope.WarnUnmatchedTables(sourceRes.Tables)
+	filemanager.SaveJsonToFile(workspace.Path("mapping", SCOPE_FILE), scopedTables)
+
+	mapping[sourceRes.AppId] = targetRes.AppId
+
 	if sourceConfig.Realm != targetConfig.Realm {
 		mapping[sourceConfig.Realm] = targetConfig.Realm
 	}
 
-	filemanager.SaveJsonToFile("mapping/mapping", mapping)
+	filemanager.SaveJsonToFile(workspace.Path("mapping", "mapping"), mapping)
+
+	if runReport != nil {
+		runReport.Set("mapping", mapping)
+		runReport.Set("scope", scopedTables)
+	}
 
 	log.Println(boldLogStyle.Render("Mapping saved"))
 
+	return mapping, nil
+}
+
+// Creates the table mapping and the report mapping which the pages and fields are rewritten with
+func CreateMappings(sourceConfig api.Quickbase, targetConfig api.Quickbase) error {
+	mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = CreateReportMapping(sourceConfig, targetConfig, mapping)
+
+	return err
+}
+
+// Pairs source and target tables which have the same name
+func MatchTables(sourceTables []api.Table, targetTables []api.Table) map[string]string {
+	mapping := make(map[string]string)
+
+	for _, sourceTable := range sourceTables {
+		for _, targetTable := range targetTables {
+			if sourceTable.Name == targetTable.Name {
+				mapping[sourceTable.ID] = targetTable.ID
+			}
+		}
+	}
+
 	return mapping
 }
 
 func VerifyFolders() {
 	for _, folder := range folders {
-		if _, err := os.Stat(folder); err != nil {
-			err = os.Mkdir(folder, 0755)
-
-			if err != nil {
-				log.Fatal(err)
-			}
-		} else {
-			if folder != "placeholders" {
-				ClearFolder(folder)
-			}
+		if err := os.MkdirAll(workspace.BasePath(folder), 0755); err != nil {
+			log.Fatal(err)
 		}
 	}
 }
 
-func ClearFolder(folderName string) {
-	folder, err := os.ReadDir(folderName)
+func newQuickbase(name string, appConfig config.AppConfig) api.Quickbase {
+	token, appToken := resolveSecretValue(appConfig.Token), resolveSecretValue(appConfig.AppToken)
+	auth, err := api.NewAuth(appConfig.Auth, token, appToken, resolveSecretValue(appConfig.Ticket))
 
 	if err != nil {
-		log.Fatal(errorStyle.Render(err.Error()))
+		log.Fatal(errorStyle.Render(name + " " + err.Error()))
+	}
+
+	client := api.NewHTTPClient()
+
+	if runCassette != nil {
+		client.Transport = runCassette.Wrap(client.Transport)
 	}
 
-	for _, file := range folder {
-		os.RemoveAll(path.Join([]string{folderName, file.Name()}...))
+	return api.Quickbase{
+		AppId:          appConfig.Id,
+		UserToken:      token,
+		AppToken:       appToken,
+		Realm:          appConfig.Realm,
+		Auth:           auth,
+		Client:         client,
+		RequestTimeout: requestTimeout,
 	}
 }
 
 func GetQuickbaseConfigs() (api.Quickbase, api.Quickbase) {
 	config := config.ReadConfig()
 
-	sourceConfig := api.Quickbase{AppId: config.Source.Id, UserToken: config.Source.Token, Realm: config.Source.Realm}
-	targetConfig := api.Quickbase{AppId: config.Target.Id, UserToken: config.Target.Token, Realm: config.Target.Realm}
+	sourceConfig := newQuickbase(ENV_SOURCE, config.Source)
+	targetConfig := newQuickbase(ENV_TARGET, config.Target)
+
+	RegisterEnvironmentSecrets(sourceConfig, config.Source)
+	RegisterEnvironmentSecrets(targetConfig, config.Target)
+	RegisterEnvironmentVariables(sourceConfig, config.Source)
+	RegisterEnvironmentVariables(targetConfig, config.Target)
 
 	return sourceConfig, targetConfig
 }
 
-func generateAppTable() table.Model {
+func generateAppTable() (table.Model, error) {
 	sourceConfig, targetConfig := GetQuickbaseConfigs()
 	config := config.ReadConfig()
 
-	sourceApp := sourceConfig.GetApp()
-	targetApp := targetConfig.GetApp()
+	sourceApp, err := sourceConfig.GetApp(runContext)
+
+	if err != nil {
+		return table.Model{}, err
+	}
+
+	targetApp, err := targetConfig.GetApp(runContext)
+
+	if err != nil {
+		return table.Model{}, err
+	}
 
 	columns := []table.Column{
 		{Title: "Type", Width: 10},
@@ -113,8 +193,8 @@ func generateAppTable() table.Model {
 	}
 
 	rows := []table.Row{
-		{"Source", config.Source.Id, sourceApp.Name, config.Source.Realm, config.Source.Token},
-		{"Target", config.Target.Id, targetApp.Name, config.Target.Realm, config.Target.Token},
+		{"Source", config.Source.Id, sourceApp.Name, config.Source.Realm, RedactSecret(config.Source.Token)},
+		{"Target", config.Target.Id, targetApp.Name, config.Target.Realm, RedactSecret(config.Target.Token)},
 	}
 
 	t := table.New(
@@ -129,18 +209,68 @@ func generateAppTable() table.Model {
 
 	t.SetStyles(s)
 
-	return t
+	return t, nil
 }
 
-func main() {
-	app := &cli.App{
-		Version: "v1.0.0",
+// Source or target app as listed by the config report, its name is left empty when the app cannot be read
+func reportApp(kind string, quickbase api.Quickbase, appConfig config.AppConfig) (map[string]string, error) {
+	runProgress.Begin(PHASE_CONFIG, kind)
+	app, err := quickbase.GetApp(runContext)
+	runProgress.Done(PHASE_CONFIG, kind, err)
+
+	return map[string]string{"type": kind, "appId": appConfig.Id, "appName": app.Name, "realm": appConfig.Realm}, err
+}
+
+// Adds the configured apps to the report, both apps are listed even when one of them cannot be read
+func ReportConfig() error {
+	sourceConfig, targetConfig := GetQuickbaseConfigs()
+	config := config.ReadConfig()
+
+	runProgress.Start(PHASE_CONFIG, 2)
+
+	source, sourceErr := reportApp("Source", sourceConfig, config.Source)
+	target, targetErr := reportApp("Target", targetConfig, config.Target)
+
+	runReport.Set("pages", config.Pages)
+	runReport.Set("apps", []map[string]string{source, target})
+
+	if sourceErr != nil {
+		return sourceErr
+	}
+
+	return targetErr
+}
+
+// Command line app with all its commands
+func NewApp() *cli.App {
+	return &cli.App{
+		Version:        "v1.0.0",
+		ExitErrHandler: RecordRunError,
+		Flags:          []cli.Flag{workspaceFlag, operatorFlag, outputFlag, timeoutFlag, requestTimeoutFlag, recordFlag, replayFlag},
+		Before: func(ctx *cli.Context) error {
+			if err := StartContext(ctx); err != nil {
+				return err
+			}
+
+			return StartCassette(ctx)
+		},
+		After: StopContext,
 		Commands: []*cli.Command{
 			{
-				Name:  "config",
-				Usage: "Prints the config to console",
+				Name:   "config",
+				Usage:  "Prints the config to console",
+				Before: StartRun,
+				After:  FinishRun,
 				Action: func(ctx *cli.Context) error {
-					appTable := generateAppTable()
+					if runReport != nil {
+						return ReportConfig()
+					}
+
+					appTable, err := generateAppTable()
+
+					if err != nil {
+						return err
+					}
 
 					fmt.Println(appTable.View())
 
@@ -157,123 +287,594 @@ func main() {
 				},
 			},
 			{
-				Name:  "run",
-				Usage: "Runs the program with both code pages and fields options",
+				Name:   "run",
+				Usage:  "Runs the program with both code pages and fields options",
+				Flags:  scopeFlags,
+				Before: StartRun,
+				After:  FinishRun,
 				Action: func(ctx *cli.Context) error {
-					VerifyFolders()
-
 					sourceConfig, targetConfig := GetQuickbaseConfigs()
 
-					CreateMapping(sourceConfig, targetConfig)
-					SavePages(sourceConfig)
-					ReplacePages(targetConfig)
-					ProcessSourceFields(sourceConfig)
-					SaveFields(targetConfig)
+					progress := StartProgress(workspace.LogFile)
+					defer progress.Stop()
 
-					return nil
+					if err := CreateMappings(sourceConfig, targetConfig); err != nil {
+						return err
+					}
+
+					// A cancelled phase stops at an item boundary and the phases after it skip every item
+					if err := SavePages(sourceConfig); err != nil {
+						return err
+					}
+
+					ReplacePages(sourceConfig, targetConfig)
+					ProcessSourceFields(sourceConfig, targetConfig)
+					SaveFields(sourceConfig, targetConfig)
+
+					return runContext.Err()
+				},
+			},
+			{
+				Name:   "mapping",
+				Usage:  "Creates the mapping from source to target",
+				Before: StartRun,
+				After:  FinishRun,
+				Action: func(ctx *cli.Context) error {
+					sourceConfig, targetConfig := GetQuickbaseConfigs()
+
+					return CreateMappings(sourceConfig, targetConfig)
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "edit",
+						Usage: "Interactively pairs source and target tables and saves the pairs as mapping overrides",
+						Action: func(ctx *cli.Context) error {
+							sourceConfig, targetConfig := GetQuickbaseConfigs()
+
+							return EditMapping(sourceConfig, targetConfig)
+						},
+					},
 				},
 			},
 			{
-				Name:  "mapping",
-				Usage: "Creates the mapping from source to target",
+				Name:   "pages",
+				Usage:  "Fetches the code pages from source app and updates them in the target app (as per the provided list in config)",
+				Flags:  scopeFlags,
+				Before: StartRun,
+				After:  FinishRun,
 				Action: func(ctx *cli.Context) error {
 					sourceConfig, targetConfig := GetQuickbaseConfigs()
 
-					folders := []string{"mapping", "tables"}
+					progress := StartProgress(workspace.LogFile)
+					defer progress.Stop()
 
-					for _, folder := range folders {
-						ClearFolder(folder)
+					if err := CreateMappings(sourceConfig, targetConfig); err != nil {
+						return err
 					}
 
-					CreateMapping(sourceConfig, targetConfig)
+					if err := SavePages(sourceConfig); err != nil {
+						return err
+					}
 
-					return nil
+					ReplacePages(sourceConfig, targetConfig)
+
+					return runContext.Err()
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "pull",
+						Usage: "Saves every code page of an environment into a local tree with a pages.json metadata file",
+						Flags: append([]cli.Flag{envFlag, pagesDirFlag}, scopeFlags...),
+						Action: func(ctx *cli.Context) error {
+							environment, _, err := GetEnvironment(ctx)
+
+							if err != nil {
+								return err
+							}
+
+							runScope = GetScope(ctx)
+
+							return PullPages(environment, ctx.String("dir"))
+						},
+					},
+					{
+						Name:  "push",
+						Usage: "Uploads the code pages of the local tree which changed since they were pulled",
+						Flags: append([]cli.Flag{
+							envFlag,
+							pagesDirFlag,
+							&cli.BoolFlag{Name: "force", Usage: "Push every page even if its content hash is unchanged"},
+						}, scopeFlags...),
+						Action: func(ctx *cli.Context) error {
+							environment, _, err := GetEnvironment(ctx)
+
+							if err != nil {
+								return err
+							}
+
+							runScope = GetScope(ctx)
+
+							return PushPages(environment, ctx.String("env"), ctx.String("dir"), ctx.Bool("force"))
+						},
+					},
+					{
+						Name:  "watch",
+						Usage: "Watches the local pages tree and pushes each saved page to the environment",
+						Flags: append([]cli.Flag{envFlag, pagesDirFlag, debounceFlag}, scopeFlags...),
+						Action: func(ctx *cli.Context) error {
+							environment, _, err := GetEnvironment(ctx)
+
+							if err != nil {
+								return err
+							}
+
+							runScope = GetScope(ctx)
+
+							return WatchPages(environment, ctx.String("env"), ctx.String("dir"), ctx.Duration("debounce"))
+						},
+					},
 				},
 			},
 			{
-				Name:  "pages",
-				Usage: "Fetches the code pages from source app and updates them in the target app (as per the provided list in config)",
+				Name:   "fieldslength",
+				Usage:  "Updates the maximum length of text and multiline fields",
+				Flags:  append(append([]cli.Flag{}, schemaFlags...), scopeFlags...),
+				Before: StartRun,
+				After:  FinishRun,
 				Action: func(ctx *cli.Context) error {
-					folders := []string{"mapping", "tables", "pages/source", "pages/target"}
+					_, targetConfig := GetQuickbaseConfigs()
+					options := GetSchemaCacheOptions(ctx)
 
-					for _, folder := range folders {
-						ClearFolder(folder)
+					if options.Offline {
+						return cli.Exit("fieldslength updates the target app and cannot run with --offline", 1)
 					}
 
-					sourceConfig, targetConfig := GetQuickbaseConfigs()
+					schema, err := SaveTargetFields(targetConfig, options)
 
-					CreateMapping(sourceConfig, targetConfig)
-					SavePages(sourceConfig)
-					ReplacePages(targetConfig)
+					if err != nil {
+						return err
+					}
 
-					return nil
+					UpdateFieldsLength(targetConfig, schema)
+
+					if err := runContext.Err(); err != nil {
+						return err
+					}
+
+					return VerifyFieldsLength(targetConfig, options)
 				},
 			},
 			{
-				Name:  "fieldslength",
-				Usage: "Updates the maximum length of text and multiline fields",
+				Name:   "verifyfields",
+				Usage:  "Verify field max length for all fields",
+				Flags:  schemaFlags,
+				Before: StartRun,
+				After:  FinishRun,
 				Action: func(ctx *cli.Context) error {
-					VerifyFolders()
+					_, targetConfig := GetQuickbaseConfigs()
 
+					return VerifyFieldsLength(targetConfig, GetSchemaCacheOptions(ctx))
+				},
+			},
+			{
+				Name:   "rules",
+				Usage:  "Generates text and file rules to include in custom data rules",
+				Flags:  schemaFlags,
+				Before: StartRun,
+				After:  FinishRun,
+				Action: func(ctx *cli.Context) error {
 					_, targetConfig := GetQuickbaseConfigs()
 
-					SaveTargetFields(targetConfig)
-					UpdateFieldsLength(targetConfig)
-					VerifyFieldsLength(targetConfig)
+					schema, err := SaveTargetFields(targetConfig, GetSchemaCacheOptions(ctx))
+
+					if err != nil {
+						return err
+					}
+
+					CustomRules(schema)
 
 					return nil
 				},
 			},
 			{
-				Name:  "verifyfields",
-				Usage: "Verify field max length for all fields",
+				Name:   "fields",
+				Usage:  "Fetch the fields from all tables in source and updates the fields to target (if Table IDs are found)",
+				Flags:  scopeFlags,
+				Before: StartRun,
+				After:  FinishRun,
 				Action: func(ctx *cli.Context) error {
-					VerifyFolders()
+					sourceConfig, targetConfig := GetQuickbaseConfigs()
 
-					_, targetConfig := GetQuickbaseConfigs()
+					if err := CreateMappings(sourceConfig, targetConfig); err != nil {
+						return err
+					}
 
-					VerifyFieldsLength(targetConfig)
+					ProcessSourceFields(sourceConfig, targetConfig)
+					SaveFields(sourceConfig, targetConfig)
 
-					return nil
+					return runContext.Err()
 				},
 			},
 			{
-				Name:  "rules",
-				Usage: "Generates text and file rules to include in custom data rules",
+				Name:  "snapshot",
+				Usage: "Saves the app, its tables, fields and code pages as a sorted tree meant to be committed",
+				Flags: []cli.Flag{envFlag, snapshotDirFlag},
 				Action: func(ctx *cli.Context) error {
-					VerifyFolders()
+					environment, _, err := GetEnvironment(ctx)
 
-					_, targetConfig := GetQuickbaseConfigs()
+					if err != nil {
+						return err
+					}
+
+					return Snapshot(environment, ctx.String("dir"))
+				},
+			},
+			{
+				Name:  "relationships",
+				Usage: "Compares the relationships, lookup and summary fields of the source tables with the target",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{Name: "create", Usage: "Create the relationships which are missing in the target"},
+				}, scopeFlags...),
+				Before: StartRun,
+				After:  FinishRun,
+				Action: func(ctx *cli.Context) error {
+					sourceConfig, targetConfig := GetQuickbaseConfigs()
 
-					SaveTargetFields(targetConfig)
-					CustomRules()
+					mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+					if err != nil {
+						return err
+					}
+
+					diffs, context, err := CompareRelationships(sourceConfig, targetConfig, mapping)
+
+					if err != nil {
+						return err
+					}
+
+					if runReport != nil {
+						runReport.Set("relationships", diffs)
+					} else {
+						PrintRelationshipDiffs(diffs)
+					}
+
+					if ctx.Bool("create") {
+						CreateMissingRelationships(context, diffs)
+
+						return runContext.Err()
+					}
+
+					for _, diff := range diffs {
+						if diff.Status == REL_MISSING {
+							log.Println(warningStyle.Render("Run with --create to create the missing relationships"))
+							break
+						}
+					}
 
 					return nil
 				},
 			},
 			{
-				Name:  "fields",
-				Usage: "Fetch the fields from all tables in source and updates the fields to target (if Table IDs are found)",
+				Name:   "tables",
+				Usage:  "Manages the tables of the target app",
+				Before: StartRun,
+				After:  FinishRun,
+				Subcommands: []*cli.Command{
+					{
+						Name:  "create-missing",
+						Usage: "Creates the source tables which do not exist in the target, with their non-formula fields",
+						Flags: append([]cli.Flag{
+							&cli.BoolFlag{Name: "dry-run", Usage: "Only list the tables which would be created"},
+						}, scopeFlags...),
+						Action: func(ctx *cli.Context) error {
+							sourceConfig, targetConfig := GetQuickbaseConfigs()
+							runScope = GetScope(ctx)
+
+							mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+							if err != nil {
+								return err
+							}
+
+							missing := MissingTables(sourceConfig, targetConfig, mapping)
+
+							for _, table := range missing {
+								log.Println(warningStyle.Render("Missing Table -- " + table.Name))
+							}
+
+							if len(missing) == 0 {
+								log.Println(boldLogStyle.Render("Every source table exists in the target"))
+								return nil
+							}
+
+							if ctx.Bool("dry-run") {
+								return nil
+							}
+
+							created := CreateMissingTables(sourceConfig, targetConfig, missing)
+
+							if runReport != nil {
+								runReport.Set("created", created)
+							}
+
+							if err := runContext.Err(); err != nil {
+								return err
+							}
+
+							// The new tables are paired by name, so a fresh mapping includes them for the next fields run
+							_, err = CreateMapping(sourceConfig, targetConfig)
+
+							return err
+						},
+					},
+				},
+			},
+			{
+				Name:   "records",
+				Usage:  "Migrates the records of reference and configuration tables",
+				Before: StartRun,
+				After:  FinishRun,
+				Subcommands: []*cli.Command{
+					{
+						Name:  "sync",
+						Usage: "Upserts the records of the tables selected with --table into their mapped target tables",
+						Flags: append([]cli.Flag{mergeFieldFlag}, scopeFlags...),
+						Action: func(ctx *cli.Context) error {
+							sourceConfig, targetConfig := GetQuickbaseConfigs()
+							runScope = GetScope(ctx)
+
+							mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+							if err != nil {
+								return err
+							}
+
+							results, err := SyncRecords(sourceConfig, targetConfig, mapping, ctx.String("merge-field"))
+
+							if err != nil {
+								return err
+							}
+
+							if runReport != nil {
+								runReport.Set("records", results)
+							} else {
+								PrintRecordsSyncResults(results)
+							}
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:   "roles",
+				Usage:  "Compares the roles and permissions of source and target",
+				Before: StartRun,
+				After:  FinishRun,
+				Subcommands: []*cli.Command{
+					{
+						Name:  "compare",
+						Usage: "Prints a matrix of the app, table and field permissions which differ for roles of the same name",
+						Flags: append([]cli.Flag{csvFlag}, scopeFlags...),
+						Action: func(ctx *cli.Context) error {
+							sourceConfig, targetConfig := GetQuickbaseConfigs()
+							runScope = GetScope(ctx)
+
+							mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+							if err != nil {
+								return err
+							}
+
+							roles, diffs, err := CompareRoles(sourceConfig, targetConfig, mapping)
+
+							if err != nil {
+								return err
+							}
+
+							if runReport != nil {
+								runReport.Set("permissions", diffs)
+							} else {
+								PrintPermissionMatrix(roles, diffs)
+							}
+
+							if path := ctx.String("csv"); path != "" {
+								SavePermissionMatrixCSV(path, roles, diffs)
+							}
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:   "vars",
+				Usage:  "Compares the app variables of source and target with the mapping applied to the source values",
+				Before: StartRun,
+				After:  FinishRun,
 				Action: func(ctx *cli.Context) error {
-					folders := []string{"mapping", "tables", "fields/source", "fields/target"}
+					sourceConfig, targetConfig := GetQuickbaseConfigs()
 
-					for _, folder := range folders {
-						ClearFolder(folder)
+					mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+					if err != nil {
+						return err
 					}
 
+					diffs, err := DiffVariables(sourceConfig, targetConfig, mapping)
+
+					if err != nil {
+						return err
+					}
+
+					if runReport != nil {
+						runReport.Set("variables", diffs)
+					} else {
+						PrintVariableDiffs(diffs)
+					}
+
+					return nil
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:  "push",
+						Usage: "Pushes the allowed app variables to the target",
+						Flags: []cli.Flag{allowVarFlag},
+						Action: func(ctx *cli.Context) error {
+							sourceConfig, targetConfig := GetQuickbaseConfigs()
+
+							mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+							if err != nil {
+								return err
+							}
+
+							diffs, err := DiffVariables(sourceConfig, targetConfig, mapping)
+
+							if err != nil {
+								return err
+							}
+
+							if runReport != nil {
+								runReport.Set("variables", diffs)
+							}
+
+							return PushVariables(targetConfig, diffs, ctx.StringSlice("var"))
+						},
+					},
+				},
+			},
+			{
+				Name:  "lint",
+				Usage: "Finds hard-coded DBIDs, realms, tokens and field IDs in formulas and code pages",
+				Flags: append([]cli.Flag{
+					&cli.BoolFlag{Name: "show-mapped", Usage: "Also print identifiers which are covered by the mapping"},
+				}, scopeFlags...),
+				Before: StartRun,
+				After:  FinishRun,
+				Action: func(ctx *cli.Context) error {
 					sourceConfig, targetConfig := GetQuickbaseConfigs()
 
-					CreateMapping(sourceConfig, targetConfig)
-					ProcessSourceFields(sourceConfig)
-					SaveFields(targetConfig)
+					mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+					if err != nil {
+						return err
+					}
+
+					findings, err := Lint(sourceConfig, targetConfig, mapping)
+
+					if err != nil {
+						return err
+					}
+
+					if runReport != nil {
+						runReport.Set("findings", findings)
+					} else {
+						PrintLintFindings(findings, ctx.Bool("show-mapped"))
+					}
+
+					for _, finding := range findings {
+						if finding.Class == LINT_UNMAPPED {
+							return errors.New("unmapped identifiers found")
+						}
+					}
+
+					return nil
+				},
+			},
+			{
+				Name:  "runs",
+				Usage: "Lists or prunes the per-run output workspaces",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "Lists all runs, newest first",
+						Action: func(ctx *cli.Context) error {
+							for _, run := range ListRuns(ctx.String("workspace")) {
+								line := run.Started.Format(time.DateTime) + "  " + run.Command + "  " + run.Name
+
+								if run.Latest {
+									line = boldLogStyle.Render(line + "  (latest)")
+								}
+
+								fmt.Println(line)
+							}
+
+							return nil
+						},
+					},
+					{
+						Name:  "prune",
+						Usage: "Removes old runs, always keeping the latest one",
+						Flags: []cli.Flag{
+							&cli.IntFlag{Name: "keep", Value: 10, Usage: "Number of most recent runs to keep (0 keeps all)"},
+							&cli.DurationFlag{Name: "older-than", Usage: "Also remove runs older than this duration"},
+						},
+						Action: func(ctx *cli.Context) error {
+							pruned := PruneRuns(ctx.String("workspace"), ctx.Int("keep"), ctx.Duration("older-than"))
+
+							for _, run := range pruned {
+								log.Println(logStyle.Render("Removed run -- " + run.Name))
+							}
+
+							log.Println(boldLogStyle.Render(fmt.Sprintf("Pruned %d runs", len(pruned))))
+
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "history",
+				Usage: "Lists the journal of writes made to Quickbase apps",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "app", Usage: "Only show writes to this app ID"},
+					&cli.StringFlag{Name: "table", Usage: "Only show writes to this table ID"},
+					&cli.StringFlag{Name: "since", Usage: "Only show writes on or after this date (YYYY-MM-DD or RFC3339)"},
+					&cli.StringFlag{Name: "until", Usage: "Only show writes before this date (YYYY-MM-DD or RFC3339)"},
+				},
+				Action: func(ctx *cli.Context) error {
+					since, err := parseDateFlag(ctx, "since")
+
+					if err != nil {
+						return err
+					}
+
+					until, err := parseDateFlag(ctx, "until")
+
+					if err != nil {
+						return err
+					}
+
+					entries := ReadJournal(ctx.String("workspace"), JournalFilter{
+						AppId:   ctx.String("app"),
+						TableId: ctx.String("table"),
+						Since:   since,
+						Until:   until,
+					})
+
+					for _, entry := range entries {
+						target := entry.PageId
+
+						if entry.FieldId != "" {
+							target = entry.TableId + "." + entry.FieldId
+						}
+
+						fmt.Printf("%s  %-8s %-10s %-20s %-22s %-14s %s -> %s  code=%s  %s\n",
+							entry.Timestamp.Format(time.DateTime), entry.Environment, entry.AppId, entry.Action, target,
+							entry.Operator, entry.BeforeHash[:min(12, len(entry.BeforeHash))], entry.AfterHash[:min(12, len(entry.AfterHash))],
+							entry.ResultCode, entry.Run)
+					}
 
 					return nil
 				},
 			},
 			{
 				Name:  "verify",
-				Usage: "Creates folders if not present, or clears files of existing folders",
+				Usage: "Creates the placeholders and runs folders if not present",
 				Action: func(ctx *cli.Context) error {
+					workspace = Workspace{Base: ctx.String("workspace")}
+
 					VerifyFolders()
 
 					return nil
@@ -281,8 +882,17 @@ func main() {
 			},
 		},
 	}
+}
+
+func main() {
+	log.SetOutput(RedactingWriter(os.Stderr))
+
+	app := NewApp()
+	ctx, stop := interruptContext(context.Background())
+	defer stop()
 
-	if err := app.Run(os.Args); err != nil {
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		cli.HandleExitCoder(err)
 		log.Fatal(errorStyle.Render(err.Error()))
 	}
 }
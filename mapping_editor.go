@@ -0,0 +1,521 @@
+package main
+
+import (
+	"app-configuration/api"
+	"app-configuration/config"
+	filemanager "app-configuration/file_manager"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	MAPPING_OVERRIDES_FOLDER = "mapping"
+	MAPPING_OVERRIDES_FILE   = "mapping"
+	EDITOR_MIN_ROWS          = 5
+)
+
+var (
+	selectedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#000000")).Background(lipgloss.Color("#008060"))
+	unmatchedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffff00"))
+	mutedStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	paneStyle      = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240")).Padding(0, 1)
+)
+
+// References to a source table found in code pages and formulas, used to preview a pairing
+type TableReferences struct {
+	Pages    []string
+	Formulas []string
+}
+
+type mappingEditor struct {
+	sourceAppId  string
+	targetAppId  string
+	sources      []api.Table
+	targets      []api.Table
+	matched      map[string]string
+	pairs        map[string]string
+	overrides    map[string]string
+	references   map[string]TableReferences
+	cursor       int
+	targetCursor int
+	pickTarget   bool
+	dirty        bool
+	message      string
+	height       int
+}
+
+// Overrides are saved per pair of apps, a source promoted to several targets has its own pairings for each
+func mappingOverridesPath(sourceAppId string, targetAppId string) string {
+	return workspace.BasePath(MAPPING_OVERRIDES_FOLDER, MAPPING_OVERRIDES_FILE+"_"+sourceAppId+"_"+targetAppId+".json")
+}
+
+// Reads the table pairings saved by mapping edit for two apps, an empty target unpairs the source table
+func ReadMappingOverrides(sourceAppId string, targetAppId string) map[string]string {
+	path := mappingOverridesPath(sourceAppId, targetAppId)
+
+	if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(workspace.BasePath(MAPPING_OVERRIDES_FOLDER, MAPPING_OVERRIDES_FILE+".json")); err == nil {
+			log.Println(warningStyle.Render("Ignoring " + MAPPING_OVERRIDES_FOLDER + "/" + MAPPING_OVERRIDES_FILE + ".json which is not tied to a target app, pair the tables again with mapping edit"))
+		}
+
+		return map[string]string{}
+	}
+
+	overrides := filemanager.ReadMapping(path)
+
+	if overrides == nil {
+		return map[string]string{}
+	}
+
+	return overrides
+}
+
+// Drops the overrides pairing a source table with a table which is not in the target app
+func ValidMappingOverrides(overrides map[string]string, targets []api.Table) map[string]string {
+	valid := make(map[string]string)
+
+	for sourceId, targetId := range overrides {
+		if targetId != "" && !slices.ContainsFunc(targets, func(table api.Table) bool { return table.ID == targetId }) {
+			log.Println(warningStyle.Render("Ignoring mapping override " + sourceId + " -> " + targetId + ", the table is not in the target app"))
+			continue
+		}
+
+		valid[sourceId] = targetId
+	}
+
+	return valid
+}
+
+func SaveMappingOverrides(sourceAppId string, targetAppId string, overrides map[string]string) {
+	if err := os.MkdirAll(workspace.BasePath(MAPPING_OVERRIDES_FOLDER), 0755); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	if err := filemanager.SaveJsonToFile(strings.TrimSuffix(mappingOverridesPath(sourceAppId, targetAppId), ".json"), overrides); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+}
+
+// Finds the code pages and formula fields of the source app which mention each source table
+func FindTableReferences(sourceConfig api.Quickbase, tables []api.Table) (map[string]TableReferences, error) {
+	log.Println(boldLogStyle.Render("Scanning pages and formulas for table references..."))
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var fetchErr error
+
+	pages := make(map[string]string)
+	formulas := make(map[string]string)
+
+	for _, pageId := range config.ReadConfig().Pages {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			strPageId := strconv.Itoa(pageId)
+			res, err := sourceConfig.GetPage(runContext, strPageId)
+
+			mutex.Lock()
+			pages[strPageId] = res.PageBody
+			fetchErr = errors.Join(fetchErr, err)
+			mutex.Unlock()
+		}()
+	}
+
+	for _, table := range tables {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			fields, err := sourceConfig.GetFields(runContext, table.ID)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			fetchErr = errors.Join(fetchErr, err)
+
+			for _, field := range fields {
+				if field.Properties.Formula != "" {
+					formulas[table.Name+" / "+field.Label] = field.Properties.Formula
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	references := make(map[string]TableReferences)
+
+	for _, table := range tables {
+		tableReferences := TableReferences{}
+
+		for pageId, body := range pages {
+			if strings.Contains(body, table.ID) {
+				tableReferences.Pages = append(tableReferences.Pages, pageId)
+			}
+		}
+
+		for name, formula := range formulas {
+			if strings.Contains(formula, table.ID) {
+				tableReferences.Formulas = append(tableReferences.Formulas, name)
+			}
+		}
+
+		sort.Strings(tableReferences.Pages)
+		sort.Strings(tableReferences.Formulas)
+
+		references[table.ID] = tableReferences
+	}
+
+	return references, nil
+}
+
+func NewMappingEditor(sourceAppId string, targetAppId string, sources []api.Table, targets []api.Table, overrides map[string]string, references map[string]TableReferences) mappingEditor {
+	matched := MatchTables(sources, targets)
+	pairs := make(map[string]string)
+
+	for sourceId, targetId := range matched {
+		pairs[sourceId] = targetId
+	}
+
+	for sourceId, targetId := range overrides {
+		pairs[sourceId] = targetId
+	}
+
+	return mappingEditor{
+		sourceAppId: sourceAppId,
+		targetAppId: targetAppId,
+		sources:     sources,
+		targets:     targets,
+		matched:     matched,
+		pairs:       pairs,
+		overrides:   overrides,
+		references:  references,
+	}
+}
+
+func (m mappingEditor) Init() tea.Cmd {
+	return nil
+}
+
+func (m mappingEditor) targetName(targetId string) string {
+	for _, table := range m.targets {
+		if table.ID == targetId {
+			return table.Name
+		}
+	}
+
+	return targetId
+}
+
+func (m mappingEditor) isTargetPaired(targetId string) bool {
+	for _, paired := range m.pairs {
+		if paired == targetId {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Builds the overrides which differ from pairing tables by name, keeping overrides of tables no longer in the source app
+func (m mappingEditor) buildOverrides() map[string]string {
+	overrides := make(map[string]string)
+
+	for sourceId, targetId := range m.overrides {
+		overrides[sourceId] = targetId
+	}
+
+	for _, table := range m.sources {
+		if m.pairs[table.ID] == m.matched[table.ID] {
+			delete(overrides, table.ID)
+		} else {
+			overrides[table.ID] = m.pairs[table.ID]
+		}
+	}
+
+	return overrides
+}
+
+func (m mappingEditor) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if size, ok := msg.(tea.WindowSizeMsg); ok {
+		m.height = size.Height
+		return m, nil
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+
+	if !ok || len(m.sources) == 0 {
+		if ok && (key.String() == "q" || key.String() == "ctrl+c") {
+			return m, tea.Quit
+		}
+
+		return m, nil
+	}
+
+	source := m.sources[m.cursor]
+	m.message = ""
+
+	if m.pickTarget {
+		switch key.String() {
+		case "up", "k":
+			m.targetCursor = max(0, m.targetCursor-1)
+		case "down", "j":
+			m.targetCursor = min(len(m.targets)-1, m.targetCursor+1)
+		case "enter", " ":
+			if len(m.targets) > 0 {
+				m.pairs[source.ID] = m.targets[m.targetCursor].ID
+				m.dirty = true
+				m.message = "Paired " + source.Name + " with " + m.targets[m.targetCursor].Name
+			}
+
+			m.pickTarget = false
+		case "esc", "tab":
+			m.pickTarget = false
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+
+		return m, nil
+	}
+
+	switch key.String() {
+	case "up", "k":
+		m.cursor = max(0, m.cursor-1)
+	case "down", "j":
+		m.cursor = min(len(m.sources)-1, m.cursor+1)
+	case "enter", "p", "tab":
+		m.pickTarget = true
+		m.targetCursor = 0
+
+		for index, table := range m.targets {
+			if table.ID == m.pairs[source.ID] {
+				m.targetCursor = index
+			}
+		}
+	case "u", "backspace", "delete":
+		m.pairs[source.ID] = ""
+		m.dirty = true
+		m.message = "Unpaired " + source.Name
+	case "r":
+		m.pairs[source.ID] = m.matched[source.ID]
+		m.dirty = true
+		m.message = "Reset " + source.Name + " to name matching"
+	case "s":
+		m.overrides = m.buildOverrides()
+		SaveMappingOverrides(m.sourceAppId, m.targetAppId, m.overrides)
+		m.dirty = false
+		m.message = fmt.Sprintf("Saved %d overrides to %s", len(m.overrides), mappingOverridesPath(m.sourceAppId, m.targetAppId))
+	case "q", "esc", "ctrl+c":
+		return m, tea.Quit
+	}
+
+	return m, nil
+}
+
+func (m mappingEditor) View() string {
+	if len(m.sources) == 0 {
+		return errorStyle.Render("No tables found in the source app") + "\n"
+	}
+
+	bottom := lipgloss.JoinVertical(lipgloss.Left, paneStyle.Render(m.preview()), m.help())
+	rows := EDITOR_MIN_ROWS
+
+	if m.height > 0 {
+		// The pane border and header take three lines, the scroll hints two more
+		rows = max(EDITOR_MIN_ROWS, m.height-lipgloss.Height(bottom)-5)
+	}
+
+	sourceLines := []string{boldLogStyle.Render("Source table -> Target table")}
+	start, end := visibleRows(len(m.sources), m.cursor, rows)
+	sourceLines = append(sourceLines, scrollHint("↑", start))
+
+	for index, table := range m.sources[start:end] {
+		index += start
+		targetId := m.pairs[table.ID]
+		line := fmt.Sprintf("%-30s -> ", truncate(table.Name, 30))
+
+		if targetId == "" {
+			line = unmatchedStyle.Render(line + "(unmatched)")
+		} else {
+			line += truncate(m.targetName(targetId), 30)
+
+			if targetId != m.matched[table.ID] {
+				line += " *"
+			}
+		}
+
+		if index == m.cursor {
+			line = selectedStyle.Render(line)
+		}
+
+		sourceLines = append(sourceLines, line)
+	}
+
+	sourceLines = append(sourceLines, scrollHint("↓", len(m.sources)-end))
+
+	targetLines := []string{boldLogStyle.Render("Target tables")}
+	start, end = visibleRows(len(m.targets), m.targetCursor, rows)
+	targetLines = append(targetLines, scrollHint("↑", start))
+
+	for index, table := range m.targets[start:end] {
+		index += start
+		line := truncate(table.Name, 30) + " " + mutedStyle.Render(table.ID)
+
+		if !m.isTargetPaired(table.ID) {
+			line = unmatchedStyle.Render(truncate(table.Name, 30) + " " + table.ID)
+		}
+
+		if m.pickTarget && index == m.targetCursor {
+			line = selectedStyle.Render(truncate(table.Name, 30) + " " + table.ID)
+		}
+
+		targetLines = append(targetLines, line)
+	}
+
+	targetLines = append(targetLines, scrollHint("↓", len(m.targets)-end))
+
+	columns := lipgloss.JoinHorizontal(lipgloss.Top,
+		paneStyle.Render(strings.Join(sourceLines, "\n")),
+		paneStyle.Render(strings.Join(targetLines, "\n")),
+	)
+
+	return lipgloss.JoinVertical(lipgloss.Left, columns, bottom) + "\n"
+}
+
+// Range of rows to render so the cursor stays in view
+func visibleRows(total int, cursor int, rows int) (int, int) {
+	if total <= rows {
+		return 0, total
+	}
+
+	start := min(max(0, cursor-rows/2), total-rows)
+
+	return start, start + rows
+}
+
+// Muted line telling how many rows are scrolled out of view
+func scrollHint(arrow string, hidden int) string {
+	if hidden <= 0 {
+		return ""
+	}
+
+	return mutedStyle.Render(fmt.Sprintf("%s %d more", arrow, hidden))
+}
+
+func (m mappingEditor) preview() string {
+	source := m.sources[m.cursor]
+	references := m.references[source.ID]
+	targetId := m.pairs[source.ID]
+
+	lines := []string{boldLogStyle.Render("Preview -- " + source.Name + " (" + source.ID + ")")}
+
+	if targetId == "" {
+		lines = append(lines, warningStyle.Render("Unpaired: references below will not be rewritten"))
+	} else {
+		lines = append(lines, "Rewrites "+source.ID+" to "+targetId+" ("+m.targetName(targetId)+")")
+	}
+
+	lines = append(lines, fmt.Sprintf("Code pages: %d %s", len(references.Pages), mutedStyle.Render(strings.Join(references.Pages, ", "))))
+	lines = append(lines, fmt.Sprintf("Formulas: %d", len(references.Formulas)))
+
+	for index, name := range references.Formulas {
+		if index == 5 {
+			lines = append(lines, mutedStyle.Render(fmt.Sprintf("  ... and %d more", len(references.Formulas)-5)))
+			break
+		}
+
+		lines = append(lines, mutedStyle.Render("  "+name))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m mappingEditor) help() string {
+	status := ""
+
+	if m.dirty {
+		status = warningStyle.Render("unsaved changes  ")
+	}
+
+	if m.message != "" {
+		status += logStyle.Render(m.message)
+	}
+
+	keys := "↑/↓ move  enter pair  u unpair  r reset  s save  q quit"
+
+	if m.pickTarget {
+		keys = "↑/↓ choose target  enter confirm  esc cancel"
+	}
+
+	return mutedStyle.Render(keys) + "\n" + status
+}
+
+func truncate(value string, length int) string {
+	if len([]rune(value)) <= length {
+		return value
+	}
+
+	return string([]rune(value)[:length-1]) + "…"
+}
+
+func EditMapping(sourceConfig api.Quickbase, targetConfig api.Quickbase) error {
+	sourceRes, err := sourceConfig.GetTables(runContext)
+
+	if err != nil {
+		return err
+	}
+
+	targetRes, err := targetConfig.GetTables(runContext)
+
+	if err != nil {
+		return err
+	}
+
+	sourceTables, targetTables := sourceRes.Tables, targetRes.Tables
+
+	sort.Slice(sourceTables, func(i, j int) bool {
+		return sourceTables[i].Name < sourceTables[j].Name
+	})
+
+	sort.Slice(targetTables, func(i, j int) bool {
+		return targetTables[i].Name < targetTables[j].Name
+	})
+
+	references, err := FindTableReferences(sourceConfig, sourceTables)
+
+	if err != nil {
+		return err
+	}
+
+	overrides := ValidMappingOverrides(ReadMappingOverrides(sourceRes.AppId, targetRes.AppId), targetTables)
+	editor := NewMappingEditor(sourceRes.AppId, targetRes.AppId, sourceTables, targetTables, overrides, references)
+
+	model, err := tea.NewProgram(editor, tea.WithAltScreen()).Run()
+
+	if err != nil {
+		return err
+	}
+
+	if model.(mappingEditor).dirty {
+		log.Println(warningStyle.Render("Exited without saving changes"))
+	}
+
+	return nil
+}
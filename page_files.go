@@ -0,0 +1,356 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	PAGES_FOLDER        = "pages"
+	PAGES_METADATA_FILE = "pages"
+	ENV_SOURCE          = "source"
+	ENV_TARGET          = "target"
+)
+
+// PageMetadata describes a code page saved in the local pages tree
+type PageMetadata struct {
+	ID     string    `json:"id"`
+	Name   string    `json:"name"`
+	Type   string    `json:"type"`
+	File   string    `json:"file"`
+	Hash   string    `json:"hash"`
+	Synced time.Time `json:"synced"`
+}
+
+// PagesMetadata is saved as pages.json next to the pulled pages
+type PagesMetadata struct {
+	AppId string         `json:"appId"`
+	Realm string         `json:"realm"`
+	Pages []PageMetadata `json:"pages"`
+}
+
+var pagesDirFlag = &cli.StringFlag{
+	Name:  "dir",
+	Value: PAGES_FOLDER,
+	Usage: "Directory of the local code pages tree",
+}
+
+var envFlag = &cli.StringFlag{
+	Name:  "env",
+	Value: ENV_SOURCE,
+	Usage: "Environment to use from config, source or target",
+}
+
+// Returns the environment selected with --env along with the other environment
+func GetEnvironment(ctx *cli.Context) (api.Quickbase, api.Quickbase, error) {
+	sourceConfig, targetConfig := GetQuickbaseConfigs()
+
+	switch ctx.String("env") {
+	case ENV_SOURCE:
+		return sourceConfig, targetConfig, nil
+	case ENV_TARGET:
+		return targetConfig, sourceConfig, nil
+	}
+
+	return api.Quickbase{}, api.Quickbase{}, errors.New("--env must be source or target")
+}
+
+// File name of a page in the local tree, pages without an extension are saved as .txt
+func pageFileName(page api.SchemaPage) string {
+	name := filemanager.SanitizeFileName(page.Name())
+
+	if name == "" {
+		name = "page-" + page.ID
+	}
+
+	if filepath.Ext(name) == "" {
+		name += ".txt"
+	}
+
+	return name
+}
+
+func pagesMetadataPath(dir string) string {
+	return filepath.Join(dir, PAGES_METADATA_FILE+".json")
+}
+
+func ReadPagesMetadata(dir string) PagesMetadata {
+	if _, err := os.Stat(pagesMetadataPath(dir)); err != nil {
+		return PagesMetadata{Pages: []PageMetadata{}}
+	}
+
+	return filemanager.ReadJSONFile[PagesMetadata](pagesMetadataPath(dir))
+}
+
+func SavePagesMetadata(dir string, metadata PagesMetadata) {
+	sort.Slice(metadata.Pages, func(i, j int) bool {
+		return metadata.Pages[i].File < metadata.Pages[j].File
+	})
+
+	if err := filemanager.SaveJsonToFile(filepath.Join(dir, PAGES_METADATA_FILE), metadata); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+}
+
+// Names of the pages of the tree by page ID
+func (m PagesMetadata) PageNames() map[string]string {
+	names := make(map[string]string)
+
+	for _, entry := range m.Pages {
+		names[entry.ID] = entry.Name
+	}
+
+	return names
+}
+
+// Saves every code page of an environment into the local pages tree
+func PullPages(environment api.Quickbase, dir string) error {
+	log.Println(boldLogStyle.Render("Pulling code pages from " + environment.AppId + "..."))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	schema, err := environment.GetSchema(runContext, environment.AppId)
+
+	if err != nil {
+		return err
+	}
+
+	metadata := PagesMetadata{AppId: environment.AppId, Realm: environment.Realm, Pages: []PageMetadata{}}
+	used := make(map[string]bool)
+	pulled := 0
+	names := make(map[string]string)
+
+	for _, page := range schema.Table.Pages {
+		names[page.ID] = page.Name()
+	}
+
+	runScope.WarnUnmatchedPages(names)
+
+	// A scoped pull only refreshes the selected pages of the tree
+	if existing := ReadPagesMetadata(dir); !runScope.IsEmpty() && existing.AppId == environment.AppId {
+		for _, entry := range existing.Pages {
+			metadata.Pages = append(metadata.Pages, entry)
+			used[entry.File] = true
+		}
+	}
+
+	for _, page := range schema.Table.Pages {
+		if runContext.Err() != nil {
+			break
+		}
+
+		if !runScope.IncludesPage(page.ID, page.Name()) {
+			continue
+		}
+
+		fileName := pageFileName(page)
+
+		for _, existing := range metadata.Pages {
+			if existing.ID == page.ID {
+				fileName = existing.File
+				delete(used, fileName)
+			}
+		}
+
+		if used[fileName] {
+			fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "-" + page.ID + filepath.Ext(fileName)
+		}
+
+		res, err := environment.GetPage(runContext, page.ID)
+
+		if err != nil {
+			log.Println(errorStyle.Render("Failed to pull Code Page -- " + page.Name() + " -- " + err.Error()))
+			continue
+		}
+
+		content := TemplatePage(ProtectSecrets(res.PageBody, environment, "Code Page "+page.Name()), environment)
+
+		filemanager.SaveFile(filepath.Join(dir, fileName), content)
+		log.Println(logStyle.Render("Pulled Code Page -- " + page.Name() + " -> " + fileName))
+
+		entry := PageMetadata{
+			ID:     page.ID,
+			Name:   page.Name(),
+			Type:   page.Type,
+			File:   fileName,
+			Hash:   HashContent(content),
+			Synced: time.Now(),
+		}
+
+		replaced := false
+
+		for index, existing := range metadata.Pages {
+			if existing.ID == page.ID {
+				metadata.Pages[index] = entry
+				replaced = true
+			}
+		}
+
+		if !replaced {
+			metadata.Pages = append(metadata.Pages, entry)
+		}
+
+		used[fileName] = true
+		pulled += 1
+	}
+
+	SavePagesMetadata(dir, metadata)
+
+	log.Println(boldLogStyle.Render(fmt.Sprintf("Pulled %d code pages into %s", pulled, dir)))
+
+	return runContext.Err()
+}
+
+// Mapping which rewrites the pages of one app for another environment
+func MappingBetween(fromAppId string, to api.Quickbase) (map[string]string, error) {
+	if fromAppId == to.AppId {
+		return map[string]string{}, nil
+	}
+
+	sourceConfig, targetConfig := GetQuickbaseConfigs()
+	mapping, err := CreateMapping(sourceConfig, targetConfig)
+
+	if err != nil || fromAppId == sourceConfig.AppId {
+		return mapping, err
+	}
+
+	inverse := make(map[string]string)
+
+	for source, target := range mapping {
+		inverse[target] = source
+	}
+
+	return inverse, nil
+}
+
+// Prepares a local page for an environment, applying the mapping and rendering its placeholders and secrets
+func RenderPage(content string, mapping map[string]string, environment api.Quickbase, source string) string {
+	for from, to := range mapping {
+		content = strings.ReplaceAll(content, from, to)
+	}
+
+	return ResolveSecrets(RenderTemplate(content, environment, source), environment, source)
+}
+
+// Renders a local page for an environment and replaces the page with the given ID, a rejected page is journaled and returned as an error
+func PushPage(environment api.Quickbase, environmentName string, entry PageMetadata, pageId string, content string, mapping map[string]string) (api.ReplacePageResponse, error) {
+	pushContent := RenderPage(content, mapping, environment, "Code Page "+entry.Name)
+
+	log.Println(logStyle.Render("Pushing Code Page -- " + entry.Name))
+
+	before, err := environment.GetPage(runContext, pageId)
+
+	if err != nil {
+		return api.ReplacePageResponse{}, err
+	}
+
+	res, err := environment.ReplacePage(runContext, pageId, pushContent)
+
+	Journal(environment, JournalEntry{
+		Environment: environmentName,
+		Action:      "ReplacePage",
+		PageId:      pageId,
+		BeforeHash:  HashContent(before.PageBody),
+		AfterHash:   HashContent(pushContent),
+		ResultCode:  ResultCode(err),
+	})
+
+	return res, err
+}
+
+// Uploads the changed pages of the local tree, skipping pages whose content hash is unchanged
+func PushPages(environment api.Quickbase, environmentName string, dir string, force bool) error {
+	log.Println(boldLogStyle.Render("Pushing code pages to " + environment.AppId + "..."))
+
+	metadata := ReadPagesMetadata(dir)
+
+	if metadata.AppId == "" {
+		return errors.New("no " + pagesMetadataPath(dir) + " found, run pages pull first")
+	}
+
+	runScope.WarnUnmatchedPages(metadata.PageNames())
+
+	sameEnvironment := metadata.AppId == environment.AppId
+	mapping := map[string]string{}
+	targetPages := make(map[string]string)
+
+	if !sameEnvironment {
+		var err error
+
+		if mapping, err = MappingBetween(metadata.AppId, environment); err != nil {
+			return err
+		}
+
+		schema, err := environment.GetSchema(runContext, environment.AppId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, page := range schema.Table.Pages {
+			targetPages[page.Name()] = page.ID
+		}
+	}
+
+	pushed := 0
+
+	for index, entry := range metadata.Pages {
+		if runContext.Err() != nil {
+			break
+		}
+
+		if !runScope.IncludesPage(entry.ID, entry.Name) {
+			continue
+		}
+
+		content := filemanager.ReadFile(filepath.Join(dir, entry.File))
+		hash := HashContent(content)
+
+		if sameEnvironment && hash == entry.Hash && !force {
+			continue
+		}
+
+		pageId := entry.ID
+
+		if !sameEnvironment {
+			id, ok := targetPages[entry.Name]
+
+			if !ok {
+				log.Println(warningStyle.Render("Code Page " + entry.Name + " does not exist in app " + environment.AppId + ", skipping"))
+				continue
+			}
+
+			pageId = id
+		}
+
+		if _, err := PushPage(environment, environmentName, entry, pageId, content, mapping); err != nil {
+			log.Println(errorStyle.Render("Failed to push Code Page -- " + entry.Name + " -- " + err.Error()))
+			continue
+		}
+
+		if sameEnvironment {
+			metadata.Pages[index].Hash = hash
+			metadata.Pages[index].Synced = time.Now()
+		}
+
+		pushed += 1
+	}
+
+	SavePagesMetadata(dir, metadata)
+
+	log.Println(boldLogStyle.Render(fmt.Sprintf("Pushed %d code pages", pushed)))
+
+	return runContext.Err()
+}
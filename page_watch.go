@@ -0,0 +1,175 @@
+package main
+
+import (
+	"app-configuration/api"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/urfave/cli/v2"
+)
+
+const WATCH_DEBOUNCE = 300 * time.Millisecond
+
+var debounceFlag = &cli.DurationFlag{
+	Name:  "debounce",
+	Value: WATCH_DEBOUNCE,
+	Usage: "Time to wait after the last save of a page before it is pushed",
+}
+
+// Watches the local pages tree and pushes each saved page to the environment once its edits settle
+func WatchPages(environment api.Quickbase, environmentName string, dir string, debounce time.Duration) error {
+	metadata := ReadPagesMetadata(dir)
+
+	if metadata.AppId == "" {
+		return errors.New("no " + pagesMetadataPath(dir) + " found, run pages pull first")
+	}
+
+	runScope.WarnUnmatchedPages(metadata.PageNames())
+
+	entries := make(map[string]PageMetadata)
+
+	for _, entry := range metadata.Pages {
+		if runScope.IncludesPage(entry.ID, entry.Name) {
+			entries[entry.File] = entry
+		}
+	}
+
+	mapping := map[string]string{}
+	pageIds := make(map[string]string)
+
+	if metadata.AppId == environment.AppId {
+		for _, entry := range entries {
+			pageIds[entry.File] = entry.ID
+		}
+	} else {
+		var err error
+
+		if mapping, err = MappingBetween(metadata.AppId, environment); err != nil {
+			return err
+		}
+
+		targetPages := make(map[string]string)
+		schema, err := environment.GetSchema(runContext, environment.AppId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, page := range schema.Table.Pages {
+			targetPages[page.Name()] = page.ID
+		}
+
+		for _, entry := range entries {
+			if id, ok := targetPages[entry.Name]; ok {
+				pageIds[entry.File] = id
+			} else {
+				log.Println(warningStyle.Render("Code Page " + entry.Name + " does not exist in app " + environment.AppId + ", it will not be pushed"))
+			}
+		}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Close()
+
+	// Editors often save by replacing the file, so the directory is watched rather than each page
+	if err := watcher.Add(dir); err != nil {
+		return err
+	}
+
+	var mutex sync.Mutex
+	timers := make(map[string]*time.Timer)
+	hashes := make(map[string]string)
+
+	// A failed push keeps the previous hash, so the next save of the page pushes it again
+	push := func(fileName string) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		entry := entries[fileName]
+		raw, err := os.ReadFile(filepath.Join(dir, fileName))
+
+		// The file may be gone for a moment while an editor replaces it
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		content := string(raw)
+		hash := HashContent(content)
+
+		if hashes[fileName] == hash {
+			return nil
+		}
+
+		if _, err := PushPage(environment, environmentName, entry, pageIds[fileName], content, mapping); err != nil {
+			return err
+		}
+
+		hashes[fileName] = hash
+		log.Println(logStyle.Render("Pushed Code Page -- " + entry.Name + " -> " + environment.AppId))
+
+		return nil
+	}
+
+	for fileName, entry := range entries {
+		hashes[fileName] = entry.Hash
+	}
+
+	log.Println(boldLogStyle.Render("Watching " + dir + " for code page changes, press Ctrl+C to stop..."))
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+
+			fileName := filepath.Base(event.Name)
+
+			if _, ok := pageIds[fileName]; !ok {
+				continue
+			}
+
+			if timer, ok := timers[fileName]; ok {
+				timer.Stop()
+			}
+
+			timers[fileName] = time.AfterFunc(debounce, func() {
+				if err := push(fileName); err != nil {
+					log.Println(errorStyle.Render("Failed to push Code Page -- " + entries[fileName].Name + " -- " + err.Error()))
+				}
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			log.Println(errorStyle.Render(err.Error()))
+		case <-runContext.Done():
+			for _, timer := range timers {
+				timer.Stop()
+			}
+
+			log.Println(boldLogStyle.Render("Stopped watching " + dir))
+
+			return nil
+		}
+	}
+}
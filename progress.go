@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/mattn/go-isatty"
+)
+
+const (
+	PHASE_MAPPING       = "Mapping"
+	PHASE_PAGES_FETCH   = "Pages fetch"
+	PHASE_PAGES_REPLACE = "Pages replace"
+	PHASE_FIELD_SCAN    = "Field scan"
+	PHASE_FIELD_UPDATE  = "Field update"
+	PHASE_FIELD_LENGTH  = "Field length"
+	PHASE_FIELD_VERIFY  = "Field verify"
+	PHASE_RULES         = "Rules"
+	PHASE_VARIABLES     = "Variables"
+	PHASE_RELATIONSHIPS = "Relationships"
+	PHASE_RECORDS       = "Records"
+	PHASE_TABLES        = "Tables"
+	PHASE_CONFIG        = "Config"
+
+	MAX_ERROR_LINES = 8
+)
+
+var runPhases = []string{PHASE_MAPPING, PHASE_PAGES_FETCH, PHASE_PAGES_REPLACE, PHASE_FIELD_SCAN, PHASE_FIELD_UPDATE}
+
+// Progress receives updates about the items processed in each phase of a run
+type Progress interface {
+	Start(phase string, total int)
+	Begin(phase string, item string)
+	Done(phase string, item string, err error)
+	Stop()
+}
+
+// Progress used by default, writing failures to the log and otherwise relying on the existing log lines
+type logProgress struct{}
+
+func (logProgress) Start(phase string, total int) {}
+
+func (logProgress) Begin(phase string, item string) {}
+
+func (logProgress) Done(phase string, item string, err error) {
+	if err != nil {
+		log.Println(errorStyle.Render(phase + " -- " + item + " -- " + err.Error()))
+	}
+}
+
+func (logProgress) Stop() {}
+
+var runProgress Progress = logProgress{}
+
+type phaseState struct {
+	total    int
+	done     int
+	failed   int
+	inFlight map[string]time.Time
+	started  time.Time
+	finished time.Time
+}
+
+type phaseStartMsg struct {
+	phase string
+	total int
+}
+
+type itemMsg struct {
+	phase string
+	item  string
+	done  bool
+	err   error
+}
+
+type logLineMsg string
+
+type dashboard struct {
+	phases   map[string]*phaseState
+	errors   []string
+	lastLine string
+	bar      progress.Model
+}
+
+// Progress shown as a live dashboard while stdout is a terminal
+type dashboardProgress struct {
+	program *tea.Program
+	done    chan struct{}
+	output  io.Writer
+}
+
+// Lines written to the log while the dashboard is running, shown in its footer instead of the terminal
+type dashboardLogWriter struct {
+	program *tea.Program
+	file    io.Writer
+	mutex   sync.Mutex
+	buffer  bytes.Buffer
+}
+
+func (w *dashboardLogWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file != nil {
+		w.file.Write(p)
+	}
+
+	w.buffer.Write(p)
+
+	for {
+		line, err := w.buffer.ReadString('\n')
+
+		if err != nil {
+			w.buffer.WriteString(line)
+			break
+		}
+
+		w.program.Send(logLineMsg(ansi.Strip(strings.TrimSpace(line))))
+	}
+
+	return len(p), nil
+}
+
+func IsTerminal() bool {
+	return isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+}
+
+// Starts the live dashboard when stdout is a terminal, falling back to plain logs otherwise
+func StartProgress(runLog io.Writer) Progress {
+	if runReport != nil || !IsTerminal() {
+		return runProgress
+	}
+
+	phases := make(map[string]*phaseState)
+
+	for _, phase := range runPhases {
+		phases[phase] = &phaseState{total: -1, inFlight: make(map[string]time.Time)}
+	}
+
+	model := dashboard{
+		phases: phases,
+		bar:    progress.New(progress.WithDefaultGradient(), progress.WithWidth(40)),
+	}
+
+	program := tea.NewProgram(model, tea.WithInput(nil), tea.WithoutSignalHandler())
+	dashboardProgress := &dashboardProgress{program: program, done: make(chan struct{}), output: log.Writer()}
+
+	go func() {
+		defer close(dashboardProgress.done)
+
+		if _, err := program.Run(); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render(err.Error()))
+		}
+	}()
+
+	log.SetOutput(RedactingWriter(&dashboardLogWriter{program: program, file: runLog}))
+
+	runProgress = dashboardProgress
+
+	return runProgress
+}
+
+func (d *dashboardProgress) Start(phase string, total int) {
+	d.program.Send(phaseStartMsg{phase: phase, total: total})
+}
+
+func (d *dashboardProgress) Begin(phase string, item string) {
+	d.program.Send(itemMsg{phase: phase, item: item})
+}
+
+func (d *dashboardProgress) Done(phase string, item string, err error) {
+	d.program.Send(itemMsg{phase: phase, item: item, done: true, err: err})
+}
+
+func (d *dashboardProgress) Stop() {
+	d.program.Quit()
+	<-d.done
+
+	log.SetOutput(d.output)
+	runProgress = logProgress{}
+}
+
+func (m dashboard) Init() tea.Cmd {
+	return nil
+}
+
+func (m dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case phaseStartMsg:
+		state := m.phases[msg.phase]
+		state.total = msg.total
+		state.started = time.Now()
+
+		if msg.total == 0 {
+			state.finished = time.Now()
+		}
+	case itemMsg:
+		state := m.phases[msg.phase]
+
+		if !msg.done {
+			state.inFlight[msg.item] = time.Now()
+			break
+		}
+
+		delete(state.inFlight, msg.item)
+		state.done += 1
+
+		if msg.err != nil {
+			state.failed += 1
+			m.errors = append(m.errors, msg.phase+" -- "+msg.item+" -- "+msg.err.Error())
+		}
+
+		if state.done >= state.total {
+			state.finished = time.Now()
+		}
+	case logLineMsg:
+		m.lastLine = string(msg)
+	case tea.WindowSizeMsg:
+		m.bar.Width = max(10, min(60, msg.Width-60))
+	}
+
+	return m, nil
+}
+
+func (m dashboard) View() string {
+	lines := make([]string, 0)
+
+	for _, phase := range runPhases {
+		state := m.phases[phase]
+
+		if state.total < 0 {
+			lines = append(lines, mutedStyle.Render(fmt.Sprintf("%-14s waiting", phase)))
+			continue
+		}
+
+		percent := 1.0
+
+		if state.total > 0 {
+			percent = float64(state.done) / float64(state.total)
+		}
+
+		status := fmt.Sprintf("%d/%d", state.done, state.total)
+
+		if state.failed > 0 {
+			status += errorStyle.Render(fmt.Sprintf(" (%d failed)", state.failed))
+		}
+
+		if !state.finished.IsZero() {
+			status += mutedStyle.Render(" " + state.finished.Sub(state.started).Round(time.Millisecond).String())
+		}
+
+		lines = append(lines, fmt.Sprintf("%-14s %s %s", lipgloss.NewStyle().Bold(true).Render(phase), m.bar.ViewAs(percent), status))
+
+		if len(state.inFlight) > 0 {
+			items := make([]string, 0, len(state.inFlight))
+
+			for item := range state.inFlight {
+				items = append(items, item)
+			}
+
+			sort.Strings(items)
+
+			if len(items) > 5 {
+				items = append(items[:5], fmt.Sprintf("+%d more", len(items)-5))
+			}
+
+			lines = append(lines, mutedStyle.Render("               in flight: "+strings.Join(items, ", ")))
+		}
+	}
+
+	errorLines := []string{boldErrorStyle.Render(fmt.Sprintf("Errors (%d)", len(m.errors)))}
+
+	for _, line := range m.errors[max(0, len(m.errors)-MAX_ERROR_LINES):] {
+		errorLines = append(errorLines, errorStyle.Render(line))
+	}
+
+	if len(m.errors) == 0 {
+		errorLines = append(errorLines, mutedStyle.Render("none"))
+	}
+
+	view := strings.Join(lines, "\n") + "\n" + paneStyle.Render(strings.Join(errorLines, "\n"))
+
+	if m.lastLine != "" {
+		view += "\n" + mutedStyle.Render(truncate(m.lastLine, 120))
+	}
+
+	return view + "\n"
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	RECORDS_BATCH_SIZE = 1000
+	RECORD_ID_FIELD    = 3
+)
+
+// RecordsSyncResult summarises the upsert of a single table
+type RecordsSyncResult struct {
+	Table      string   `json:"table"`
+	MergeField string   `json:"mergeField"`
+	Inserted   int      `json:"inserted"`
+	Updated    int      `json:"updated"`
+	Skipped    int      `json:"skipped"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+var mergeFieldFlag = &cli.StringFlag{
+	Name:  "merge-field",
+	Usage: "Field matching source records with target records (label or ID), defaults to the key field of each table",
+}
+
+// Built-in, formula, lookup, summary and file fields can not be written to
+func isWritableField(field api.Field) bool {
+	return field.ID > 5 && field.Mode == "" && field.FieldType != "file" && field.FieldType != "recordid"
+}
+
+// Finds the merge field of a table, either the given label or ID or the key field when it is not the record ID
+func findMergeField(table api.Table, fields map[int]api.Field, mergeField string) (api.Field, error) {
+	if mergeField == "" {
+		if table.KeyFieldID == RECORD_ID_FIELD || table.KeyFieldID == 0 {
+			return api.Field{}, errors.New("table " + table.Name + " is keyed by Record ID#, pass a unique field with --merge-field")
+		}
+
+		return fields[table.KeyFieldID], nil
+	}
+
+	for _, field := range fields {
+		if matchesAny([]string{mergeField}, strconv.Itoa(field.ID), field.Label) {
+			return field, nil
+		}
+	}
+
+	return api.Field{}, errors.New("merge field " + mergeField + " does not exist in table " + table.Name)
+}
+
+// User fields are read as objects but written as an email address
+func recordValue(value api.RecordValue) api.RecordValue {
+	if user, ok := value.Value.(map[string]any); ok {
+		if email, ok := user["email"]; ok {
+			return api.RecordValue{Value: email}
+		}
+	}
+
+	return value
+}
+
+// Upserts the records of a source table into its mapped target table, matching fields by label
+func SyncTableRecords(sourceConfig api.Quickbase, targetConfig api.Quickbase, table api.Table, targetId string, sourceFields map[int]api.Field, targetFields map[int]api.Field, mergeField string) RecordsSyncResult {
+	result := RecordsSyncResult{Table: table.Name, Errors: []string{}}
+
+	merge, err := findMergeField(table, sourceFields, mergeField)
+
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	result.MergeField = merge.Label
+	targetMergeId, ok := fieldIdByLabel(targetFields, merge.Label)
+
+	if !ok {
+		result.Errors = append(result.Errors, "merge field "+merge.Label+" does not exist in the target table")
+		return result
+	}
+
+	if !isWritableField(targetFields[targetMergeId]) {
+		result.Errors = append(result.Errors, "merge field "+merge.Label+" is not writable in the target table, pick another with --merge-field")
+		return result
+	}
+
+	// Source field ID to target field ID for every field which can be written in the target
+	fieldMapping := make(map[int]int)
+	sourceIds := make([]int, 0)
+
+	for id, field := range sourceFields {
+		if id != merge.ID && (!isWritableField(field) || !runScope.IncludesField(field)) {
+			continue
+		}
+
+		targetId, ok := fieldIdByLabel(targetFields, field.Label)
+
+		if !ok || !isWritableField(targetFields[targetId]) {
+			continue
+		}
+
+		fieldMapping[id] = targetId
+		sourceIds = append(sourceIds, id)
+	}
+
+	sort.Ints(sourceIds)
+
+	records := make([]api.Record, 0)
+	sourceRecords, err := sourceConfig.QueryRecords(runContext, table.ID, sourceIds)
+
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	for _, record := range sourceRecords {
+		mapped := make(api.Record)
+
+		for id, value := range record {
+			sourceId, err := strconv.Atoi(id)
+
+			if err != nil {
+				continue
+			}
+
+			if targetId, ok := fieldMapping[sourceId]; ok {
+				mapped[strconv.Itoa(targetId)] = recordValue(value)
+			}
+		}
+
+		records = append(records, mapped)
+	}
+
+	for start := 0; start < len(records); start += RECORDS_BATCH_SIZE {
+		if runContext.Err() != nil {
+			break
+		}
+
+		batch := records[start:min(start+RECORDS_BATCH_SIZE, len(records))]
+		content, _ := json.Marshal(batch)
+
+		res, upsertErr := targetConfig.UpsertRecords(runContext, targetId, batch, targetMergeId)
+
+		if upsertErr != nil {
+			result.Errors = append(result.Errors, upsertErr.Error())
+			result.Skipped += len(batch)
+		}
+
+		result.Inserted += len(res.Metadata.CreatedRecordIDs)
+		result.Updated += len(res.Metadata.UpdatedRecordIDs)
+		result.Skipped += len(res.Metadata.UnchangedRecordIDs)
+
+		for line, lineErrors := range res.Metadata.LineErrors {
+			result.Skipped += 1
+
+			for _, lineError := range lineErrors {
+				result.Errors = append(result.Errors, "record "+line+": "+lineError)
+			}
+		}
+
+		Journal(targetConfig, JournalEntry{
+			Action:     "UpsertRecords",
+			TableId:    targetId,
+			FieldId:    strconv.Itoa(targetMergeId),
+			AfterHash:  HashContent(string(content)),
+			ResultCode: ResultCode(upsertErr),
+		})
+	}
+
+	return result
+}
+
+// Syncs the records of the tables selected with --table into their mapped target tables
+func SyncRecords(sourceConfig api.Quickbase, targetConfig api.Quickbase, mapping map[string]string, mergeField string) ([]RecordsSyncResult, error) {
+	if len(runScope.Tables) == 0 {
+		return nil, errors.New("select the tables to sync with --table")
+	}
+
+	log.Println(boldLogStyle.Render("Syncing records..."))
+
+	tables := make([]api.Table, 0)
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](workspace.Path("tables", sourceConfig.AppId+".json")) {
+		if runScope.IncludesTable(table.ID, table.Name, table.Alias) {
+			tables = append(tables, table)
+		}
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+
+	results := make([]RecordsSyncResult, 0)
+
+	runProgress.Start(PHASE_RECORDS, len(tables))
+
+	for _, table := range tables {
+		if runContext.Err() != nil {
+			break
+		}
+
+		runProgress.Begin(PHASE_RECORDS, table.Name)
+
+		targetId, ok := mapping[table.ID]
+
+		if !ok {
+			runProgress.Done(PHASE_RECORDS, table.Name, errors.New("table is not mapped to the target"))
+			continue
+		}
+
+		fields, err := fetchFieldsByTable(sourceConfig, []string{table.ID})
+
+		if err != nil {
+			results = append(results, RecordsSyncResult{Table: table.Name, Errors: []string{err.Error()}})
+			runProgress.Done(PHASE_RECORDS, table.Name, err)
+			continue
+		}
+
+		targetFields, err := fetchFieldsByTable(targetConfig, []string{targetId})
+
+		if err != nil {
+			results = append(results, RecordsSyncResult{Table: table.Name, Errors: []string{err.Error()}})
+			runProgress.Done(PHASE_RECORDS, table.Name, err)
+			continue
+		}
+
+		result := SyncTableRecords(sourceConfig, targetConfig, table, targetId, fields[table.ID], targetFields[targetId], mergeField)
+		results = append(results, result)
+
+		var syncErr error
+
+		if len(result.Errors) > 0 {
+			syncErr = errors.New(result.Errors[0])
+		}
+
+		runProgress.Done(PHASE_RECORDS, table.Name, syncErr)
+	}
+
+	return results, runContext.Err()
+}
+
+func PrintRecordsSyncResults(results []RecordsSyncResult) {
+	for _, result := range results {
+		line := fmt.Sprintf("%-30s %-24s %5d inserted %5d updated %5d skipped", result.Table, result.MergeField, result.Inserted, result.Updated, result.Skipped)
+
+		if len(result.Errors) > 0 {
+			fmt.Println(warningStyle.Render(line))
+		} else {
+			fmt.Println(logStyle.Render(line))
+		}
+
+		for _, err := range result.Errors {
+			fmt.Println(errorStyle.Render("    " + err))
+		}
+	}
+}
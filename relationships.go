@@ -0,0 +1,388 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	REL_OK          = "ok"
+	REL_MISSING     = "missing"
+	REL_WRONG_TABLE = "wrong-table"
+	REL_INCOMPLETE  = "incomplete"
+	REL_EXTERNAL    = "external"
+)
+
+// Accumulation types of the relationship API for each summary function of a field
+var accumulationTypes = map[string]string{
+	"count":          "COUNT",
+	"total":          "SUM",
+	"average":        "AVG",
+	"maximum":        "MAX",
+	"minimum":        "MIN",
+	"std-dev":        "STD-DEV",
+	"distinct-count": "DISTINCT-COUNT",
+	"combined-text":  "COMBINED-TEXT",
+	"combined-user":  "COMBINED-USER",
+}
+
+// RelationshipDiff compares a relationship of the source with the target
+type RelationshipDiff struct {
+	ChildTable       string   `json:"childTable"`
+	ParentTable      string   `json:"parentTable"`
+	ForeignKey       string   `json:"foreignKey"`
+	Status           string   `json:"status"`
+	Detail           string   `json:"detail,omitempty"`
+	MissingLookups   []string `json:"missingLookups,omitempty"`
+	MissingSummaries []string `json:"missingSummaries,omitempty"`
+
+	source        api.Relationship
+	targetChildId string
+	targetParent  string
+}
+
+type relationshipContext struct {
+	sourceConfig api.Quickbase
+	targetConfig api.Quickbase
+	mapping      map[string]string
+	sourceTables map[string]api.Table
+	targetTables map[string]api.Table
+	sourceFields map[string]map[int]api.Field
+	targetFields map[string]map[int]api.Field
+}
+
+func fieldIdByLabel(fields map[int]api.Field, label string) (int, bool) {
+	for id, field := range fields {
+		if field.Label == label {
+			return id, true
+		}
+	}
+
+	return 0, false
+}
+
+// Labels of the fields which are not found in the other list
+func missingLabels(fields []api.RelationshipField, others []api.RelationshipField) []string {
+	labels := make(map[string]bool)
+
+	for _, field := range others {
+		labels[field.Label] = true
+	}
+
+	missing := make([]string, 0)
+
+	for _, field := range fields {
+		if !labels[field.Label] {
+			missing = append(missing, field.Label)
+		}
+	}
+
+	return missing
+}
+
+// Pairs each source relationship with a target relationship by the label of its reference field. As the reference field
+// may have been renamed, a relationship left unpaired takes the only unclaimed target relationship to the same parent
+func (c relationshipContext) pairRelationships(sources []api.Relationship, targets []api.Relationship) []*api.Relationship {
+	found := make([]*api.Relationship, len(sources))
+	claimed := make(map[int]bool)
+
+	for i, relationship := range sources {
+		if _, ok := c.mapping[relationship.ParentTableID]; !ok {
+			continue
+		}
+
+		for index, target := range targets {
+			if !claimed[index] && target.ForeignKeyField.Label == relationship.ForeignKeyField.Label {
+				found[i] = &targets[index]
+				claimed[index] = true
+				break
+			}
+		}
+	}
+
+	for i, relationship := range sources {
+		parentId, ok := c.mapping[relationship.ParentTableID]
+
+		if !ok || found[i] != nil {
+			continue
+		}
+
+		candidates := make([]int, 0)
+
+		for index, target := range targets {
+			if !claimed[index] && target.ParentTableID == parentId {
+				candidates = append(candidates, index)
+			}
+		}
+
+		if len(candidates) == 1 {
+			found[i] = &targets[candidates[0]]
+			claimed[candidates[0]] = true
+		}
+	}
+
+	return found
+}
+
+func (c relationshipContext) compare(relationship api.Relationship, found *api.Relationship) RelationshipDiff {
+	diff := RelationshipDiff{
+		ChildTable:  c.sourceTables[relationship.ChildTableID].Name,
+		ParentTable: c.sourceTables[relationship.ParentTableID].Name,
+		ForeignKey:  relationship.ForeignKeyField.Label,
+		source:      relationship,
+	}
+
+	if diff.ParentTable == "" {
+		diff.ParentTable = relationship.ParentTableID
+	}
+
+	parentId, ok := c.mapping[relationship.ParentTableID]
+
+	if !ok {
+		diff.Status = REL_EXTERNAL
+		diff.Detail = "parent table is not mapped to the target"
+
+		return diff
+	}
+
+	diff.targetChildId = c.mapping[relationship.ChildTableID]
+	diff.targetParent = parentId
+
+	if found != nil && found.ParentTableID != parentId {
+		diff.Status = REL_WRONG_TABLE
+		diff.Detail = "points to " + c.targetTables[found.ParentTableID].Name + " (" + found.ParentTableID + ") instead of " + c.targetTables[parentId].Name
+
+		return diff
+	}
+
+	if found == nil {
+		diff.Status = REL_MISSING
+		diff.Detail = "no relationship to " + c.targetTables[parentId].Name + " in the target"
+
+		return diff
+	}
+
+	diff.Status = REL_OK
+	diff.MissingLookups = missingLabels(relationship.LookupFields, found.LookupFields)
+	diff.MissingSummaries = missingLabels(relationship.SummaryFields, found.SummaryFields)
+
+	if len(diff.MissingLookups) > 0 || len(diff.MissingSummaries) > 0 {
+		diff.Status = REL_INCOMPLETE
+		diff.Detail = fmt.Sprintf("%d lookup and %d summary fields missing in the target", len(diff.MissingLookups), len(diff.MissingSummaries))
+	}
+
+	return diff
+}
+
+// Compares the relationships of the mapped source tables with their target tables
+func CompareRelationships(sourceConfig api.Quickbase, targetConfig api.Quickbase, mapping map[string]string) ([]RelationshipDiff, relationshipContext, error) {
+	log.Println(boldLogStyle.Render("Comparing relationships..."))
+
+	context := relationshipContext{
+		sourceConfig: sourceConfig,
+		targetConfig: targetConfig,
+		mapping:      mapping,
+		sourceTables: make(map[string]api.Table),
+		targetTables: make(map[string]api.Table),
+	}
+
+	sourceIds := make([]string, 0)
+	targetIds := make([]string, 0)
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](workspace.Path("tables", sourceConfig.AppId+".json")) {
+		context.sourceTables[table.ID] = table
+		sourceIds = append(sourceIds, table.ID)
+	}
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](workspace.Path("tables", targetConfig.AppId+".json")) {
+		context.targetTables[table.ID] = table
+		targetIds = append(targetIds, table.ID)
+	}
+
+	var err error
+
+	if context.sourceFields, err = fetchFieldsByTable(sourceConfig, sourceIds); err != nil {
+		return nil, context, err
+	}
+
+	if context.targetFields, err = fetchFieldsByTable(targetConfig, targetIds); err != nil {
+		return nil, context, err
+	}
+
+	sort.Strings(sourceIds)
+
+	diffs := make([]RelationshipDiff, 0)
+
+	for _, tableId := range sourceIds {
+		table := context.sourceTables[tableId]
+		targetId, ok := mapping[tableId]
+
+		if !ok || !runScope.IncludesTable(table.ID, table.Name, table.Alias) {
+			continue
+		}
+
+		sourceRelationships, err := sourceConfig.GetRelationships(runContext, tableId)
+
+		if err != nil {
+			return diffs, context, err
+		}
+
+		if len(sourceRelationships) == 0 {
+			continue
+		}
+
+		targetRelationships, err := targetConfig.GetRelationships(runContext, targetId)
+
+		if err != nil {
+			return diffs, context, err
+		}
+
+		found := context.pairRelationships(sourceRelationships, targetRelationships)
+
+		for index, relationship := range sourceRelationships {
+			diffs = append(diffs, context.compare(relationship, found[index]))
+		}
+	}
+
+	return diffs, context, nil
+}
+
+func PrintRelationshipDiffs(diffs []RelationshipDiff) {
+	counts := make(map[string]int)
+
+	for _, diff := range diffs {
+		counts[diff.Status] += 1
+
+		line := fmt.Sprintf("%-11s %-24s -> %-24s %-24s %s", diff.Status, diff.ChildTable, diff.ParentTable, diff.ForeignKey, diff.Detail)
+
+		switch diff.Status {
+		case REL_MISSING, REL_WRONG_TABLE:
+			line = errorStyle.Render(line)
+		case REL_INCOMPLETE, REL_EXTERNAL:
+			line = warningStyle.Render(line)
+		default:
+			line = logStyle.Render(line)
+		}
+
+		fmt.Println(line)
+
+		for _, label := range diff.MissingLookups {
+			fmt.Println(mutedStyle.Render("    missing lookup  " + label))
+		}
+
+		for _, label := range diff.MissingSummaries {
+			fmt.Println(mutedStyle.Render("    missing summary " + label))
+		}
+	}
+
+	log.Println(boldLogStyle.Render(fmt.Sprintf("%d ok, %d incomplete, %d missing, %d wrong table, %d external", counts[REL_OK], counts[REL_INCOMPLETE], counts[REL_MISSING], counts[REL_WRONG_TABLE], counts[REL_EXTERNAL])))
+}
+
+// Builds the target relationship of a missing one, mapping its lookup and summary fields by label
+func (c relationshipContext) relationshipBody(diff RelationshipDiff) api.CreateRelationshipBody {
+	relationship := diff.source
+	body := api.CreateRelationshipBody{ParentTableID: diff.targetParent}
+	body.ForeignKeyField.Label = relationship.ForeignKeyField.Label
+
+	sourceChild := c.sourceFields[relationship.ChildTableID]
+	sourceParent := c.sourceFields[relationship.ParentTableID]
+	targetChild := c.targetFields[diff.targetChildId]
+	targetParent := c.targetFields[diff.targetParent]
+
+	for _, lookup := range relationship.LookupFields {
+		parentField, ok := sourceParent[sourceChild[lookup.ID].Properties.LookupTargetFieldID]
+
+		if !ok {
+			log.Println(warningStyle.Render("Lookup " + lookup.Label + " has no source field in " + diff.ParentTable + ", skipping"))
+			continue
+		}
+
+		fieldId, ok := fieldIdByLabel(targetParent, parentField.Label)
+
+		if !ok {
+			log.Println(warningStyle.Render("Lookup " + lookup.Label + " needs field " + parentField.Label + " which does not exist in the target, skipping"))
+			continue
+		}
+
+		body.LookupFieldIDs = append(body.LookupFieldIDs, fieldId)
+	}
+
+	for _, summary := range relationship.SummaryFields {
+		field := sourceParent[summary.ID]
+		accumulation, ok := accumulationTypes[strings.ToLower(field.Properties.SummaryFunction)]
+
+		if !ok {
+			log.Println(warningStyle.Render("Summary " + summary.Label + " uses " + field.Properties.SummaryFunction + " which can not be created, skipping"))
+			continue
+		}
+
+		summaryField := api.RelationshipSummaryField{Label: summary.Label, AccumulationType: accumulation}
+
+		if accumulation != "COUNT" {
+			childField, ok := sourceChild[field.Properties.SummaryTargetFieldID]
+
+			if !ok {
+				log.Println(warningStyle.Render("Summary " + summary.Label + " has no source field in " + diff.ChildTable + ", skipping"))
+				continue
+			}
+
+			fieldId, ok := fieldIdByLabel(targetChild, childField.Label)
+
+			if !ok {
+				log.Println(warningStyle.Render("Summary " + summary.Label + " needs field " + childField.Label + " which does not exist in the target, skipping"))
+				continue
+			}
+
+			summaryField.SummaryFid = fieldId
+		}
+
+		body.SummaryFields = append(body.SummaryFields, summaryField)
+	}
+
+	return body
+}
+
+// Creates the relationships which are missing in the target
+func CreateMissingRelationships(context relationshipContext, diffs []RelationshipDiff) {
+	missing := make([]RelationshipDiff, 0)
+
+	for _, diff := range diffs {
+		if diff.Status == REL_MISSING {
+			missing = append(missing, diff)
+		}
+	}
+
+	runProgress.Start(PHASE_RELATIONSHIPS, len(missing))
+
+	for _, diff := range missing {
+		if runContext.Err() != nil {
+			break
+		}
+
+		item := diff.ChildTable + " -> " + diff.ParentTable
+		runProgress.Begin(PHASE_RELATIONSHIPS, item)
+
+		body := context.relationshipBody(diff)
+		content, _ := json.Marshal(body)
+
+		log.Println(logStyle.Render("Creating Relationship -- " + item))
+
+		res, relErr := context.targetConfig.CreateRelationship(runContext, diff.targetChildId, body)
+
+		Journal(context.targetConfig, JournalEntry{
+			Action:     "CreateRelationship",
+			TableId:    diff.targetChildId,
+			FieldId:    strconv.Itoa(res.ID),
+			AfterHash:  HashContent(string(content)),
+			ResultCode: ResultCode(relErr),
+		})
+
+		runProgress.Done(PHASE_RELATIONSHIPS, item, relErr)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"app-configuration/api"
+	"reflect"
+	"testing"
+)
+
+func TestCompareRelationships(t *testing.T) {
+	context := relationshipContext{
+		mapping: map[string]string{"bsrc00001": "btgt00001", "bsrc00002": "btgt00002", "bsrc00003": "btgt00003"},
+		sourceTables: map[string]api.Table{
+			"bsrc00001": {ID: "bsrc00001", Name: "Requests"},
+			"bsrc00002": {ID: "bsrc00002", Name: "Employees"},
+			"bsrc00003": {ID: "bsrc00003", Name: "Projects"},
+		},
+		targetTables: map[string]api.Table{
+			"btgt00001": {ID: "btgt00001", Name: "Requests"},
+			"btgt00002": {ID: "btgt00002", Name: "Employees"},
+			"btgt00003": {ID: "btgt00003", Name: "Projects"},
+		},
+	}
+
+	relationship := func(parentId string, label string) api.Relationship {
+		return api.Relationship{ParentTableID: parentId, ChildTableID: "bsrc00001", ForeignKeyField: api.RelationshipField{Label: label}}
+	}
+
+	tests := []struct {
+		name    string
+		sources []api.Relationship
+		targets []api.Relationship
+		want    []string
+	}{
+		{
+			name:    "same label",
+			sources: []api.Relationship{relationship("bsrc00002", "Requester")},
+			targets: []api.Relationship{relationship("btgt00002", "Requester")},
+			want:    []string{REL_OK},
+		},
+		{
+			name:    "renamed reference field",
+			sources: []api.Relationship{relationship("bsrc00002", "Requester")},
+			targets: []api.Relationship{relationship("btgt00002", "Requested By")},
+			want:    []string{REL_OK},
+		},
+		{
+			name:    "second relationship to the same parent is missing",
+			sources: []api.Relationship{relationship("bsrc00002", "Approver"), relationship("bsrc00002", "Requester")},
+			targets: []api.Relationship{relationship("btgt00002", "Requester")},
+			want:    []string{REL_MISSING, REL_OK},
+		},
+		{
+			name:    "renamed relationship with several candidates is missing",
+			sources: []api.Relationship{relationship("bsrc00002", "Approver")},
+			targets: []api.Relationship{relationship("btgt00002", "Requested By"), relationship("btgt00002", "Approved By")},
+			want:    []string{REL_MISSING},
+		},
+		{
+			name:    "same label to another parent",
+			sources: []api.Relationship{relationship("bsrc00002", "Owner")},
+			targets: []api.Relationship{relationship("btgt00003", "Owner")},
+			want:    []string{REL_WRONG_TABLE},
+		},
+		{
+			name:    "parent not mapped",
+			sources: []api.Relationship{relationship("bother001", "Customer")},
+			targets: []api.Relationship{relationship("btgt00002", "Customer")},
+			want:    []string{REL_EXTERNAL},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found := context.pairRelationships(test.sources, test.targets)
+			got := make([]string, 0)
+
+			for index, relationship := range test.sources {
+				got = append(got, context.compare(relationship, found[index]).Status)
+			}
+
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("statuses = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"app-configuration/config"
+	"encoding/json"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	OUTPUT_TEXT = "text"
+	OUTPUT_JSON = "json"
+)
+
+// ReportItem is the result of a single item processed in a phase
+type ReportItem struct {
+	Phase      string `json:"phase"`
+	Item       string `json:"item"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type ReportTiming struct {
+	Phase      string `json:"phase"`
+	Total      int    `json:"total"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Report is the single JSON document written to stdout by a command run with --output json
+type Report struct {
+	Command    string         `json:"command"`
+	Status     string         `json:"status"`
+	RunStatus  string         `json:"runStatus"`
+	Inputs     map[string]any `json:"inputs"`
+	Results    []ReportItem   `json:"results"`
+	Data       map[string]any `json:"data,omitempty"`
+	Errors     []string       `json:"errors"`
+	Timings    []ReportTiming `json:"timings"`
+	Started    time.Time      `json:"started"`
+	Finished   time.Time      `json:"finished"`
+	DurationMs int64          `json:"durationMs"`
+	mutex      sync.Mutex
+	phases     map[string]*phaseTiming
+	begun      map[string]time.Time
+}
+
+type phaseTiming struct {
+	total   int
+	started time.Time
+	ended   time.Time
+}
+
+// Report of the current command, nil unless --output json was given
+var runReport *Report
+
+var outputFlag = &cli.StringFlag{
+	Name:  "output",
+	Value: OUTPUT_TEXT,
+	Usage: "Output format, text or json (json writes a single document to stdout and logs to stderr)",
+	Action: func(ctx *cli.Context, value string) error {
+		if value != OUTPUT_TEXT && value != OUTPUT_JSON {
+			return cli.Exit("--output must be text or json", 1)
+		}
+
+		return nil
+	},
+}
+
+func IsJSONOutput(ctx *cli.Context) bool {
+	return ctx.String("output") == OUTPUT_JSON
+}
+
+func NewReport(ctx *cli.Context) *Report {
+	appConfig := config.ReadConfig()
+
+	flags := make(map[string]any)
+
+	for _, name := range ctx.FlagNames() {
+		flags[name] = ctx.Value(name)
+	}
+
+	return &Report{
+		Command: ctx.Command.FullName(),
+		Status:  "ok",
+		Inputs: map[string]any{
+			"args":   ctx.Args().Slice(),
+			"flags":  flags,
+			"source": map[string]string{"appId": appConfig.Source.Id, "realm": appConfig.Source.Realm},
+			"target": map[string]string{"appId": appConfig.Target.Id, "realm": appConfig.Target.Realm},
+			"pages":  appConfig.Pages,
+		},
+		Results: []ReportItem{},
+		Data:    make(map[string]any),
+		Errors:  []string{},
+		Timings: []ReportTiming{},
+		Started: time.Now(),
+		phases:  make(map[string]*phaseTiming),
+		begun:   make(map[string]time.Time),
+	}
+}
+
+func (r *Report) Set(key string, value any) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Data[key] = value
+}
+
+// Records how the run ended, a run which did not complete is reported with an error status
+func (r *Report) Finish(runStatus string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.RunStatus = runStatus
+
+	// An error already reported by its phase item is not listed twice
+	if err != nil && !slices.ContainsFunc(r.Errors, func(reported string) bool { return strings.HasSuffix(reported, " -- "+err.Error()) }) {
+		r.Errors = append(r.Errors, err.Error())
+	}
+
+	if runStatus != "completed" {
+		r.Status = "error"
+	}
+}
+
+// Writes the report to stdout
+func (r *Report) Write() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Finished = time.Now()
+	r.DurationMs = r.Finished.Sub(r.Started).Milliseconds()
+	r.Timings = []ReportTiming{}
+
+	for phase, timing := range r.phases {
+		ended := timing.ended
+
+		if ended.IsZero() {
+			ended = r.Finished
+		}
+
+		r.Timings = append(r.Timings, ReportTiming{Phase: phase, Total: timing.total, DurationMs: ended.Sub(timing.started).Milliseconds()})
+	}
+
+	sort.Slice(r.Timings, func(i, j int) bool {
+		return r.phases[r.Timings[i].Phase].started.Before(r.phases[r.Timings[j].Phase].started)
+	})
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+// Progress which records every item into the report before passing it on
+type reportProgress struct {
+	report *Report
+	next   Progress
+}
+
+func (p reportProgress) Start(phase string, total int) {
+	p.report.mutex.Lock()
+	p.report.phases[phase] = &phaseTiming{total: total, started: time.Now()}
+	p.report.mutex.Unlock()
+
+	p.next.Start(phase, total)
+}
+
+func (p reportProgress) Begin(phase string, item string) {
+	p.report.mutex.Lock()
+	p.report.begun[phase+"\x00"+item] = time.Now()
+	p.report.mutex.Unlock()
+
+	p.next.Begin(phase, item)
+}
+
+func (p reportProgress) Done(phase string, item string, err error) {
+	p.report.mutex.Lock()
+
+	result := ReportItem{Phase: phase, Item: item, Status: "ok"}
+
+	if begun, ok := p.report.begun[phase+"\x00"+item]; ok {
+		result.DurationMs = time.Since(begun).Milliseconds()
+		delete(p.report.begun, phase+"\x00"+item)
+	}
+
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+		p.report.Errors = append(p.report.Errors, phase+" -- "+item+" -- "+err.Error())
+	}
+
+	p.report.Results = append(p.report.Results, result)
+
+	if timing, ok := p.report.phases[phase]; ok {
+		timing.ended = time.Now()
+	}
+
+	p.report.mutex.Unlock()
+
+	p.next.Done(phase, item, err)
+}
+
+func (p reportProgress) Stop() {
+	p.next.Stop()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"errors"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const REPORTS_MAPPING_FILE = "reports"
+
+var (
+	qidRegex = regexp.MustCompile(`\bqid=(\d+)`)
+
+	// Tokens which set the table a following qid= belongs to, a DBID, a _DBID_ alias or Dbid() for the own table
+	reportContextRegex = regexp.MustCompile(`(?i)\[?_DBID_[A-Z0-9_]+\]?|\bb[a-z0-9]{8}\b|\bDbid\(\)`)
+)
+
+// ReportMapping pairs the report IDs of each source table with the reports of the same name in its target table
+type ReportMapping map[string]map[string]string
+
+// Pairs reports by name, reports whose name is not unique in either table are left out
+func matchReports(sourceReports []api.Report, targetReports []api.Report) map[string]string {
+	countNames := func(reports []api.Report) map[string]int {
+		counts := make(map[string]int)
+
+		for _, report := range reports {
+			counts[report.Name] += 1
+		}
+
+		return counts
+	}
+
+	sourceNames := countNames(sourceReports)
+	targetNames := countNames(targetReports)
+	pairs := make(map[string]string)
+
+	for _, source := range sourceReports {
+		if sourceNames[source.Name] != 1 || targetNames[source.Name] != 1 {
+			continue
+		}
+
+		for _, target := range targetReports {
+			if target.Name == source.Name {
+				pairs[source.ID] = target.ID
+			}
+		}
+	}
+
+	return pairs
+}
+
+// Lists the reports of every scoped table and pairs them with the reports of its target table
+func CreateReportMapping(sourceConfig api.Quickbase, targetConfig api.Quickbase, mapping map[string]string) (ReportMapping, error) {
+	log.Println(boldLogStyle.Render("Mapping reports..."))
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var fetchErr error
+
+	reports := make(ReportMapping)
+	scopedTables := filemanager.ReadJSONFile[[]string](workspace.Path("mapping", SCOPE_FILE+".json"))
+
+	for _, tableId := range scopedTables {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			sourceReports, sourceErr := sourceConfig.GetReports(runContext, tableId)
+			targetReports, targetErr := targetConfig.GetReports(runContext, mapping[tableId])
+
+			mutex.Lock()
+			reports[tableId] = matchReports(sourceReports, targetReports)
+			fetchErr = errors.Join(fetchErr, sourceErr, targetErr)
+			mutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if fetchErr != nil {
+		return reports, fetchErr
+	}
+
+	filemanager.SaveJsonToFile(workspace.Path("mapping", REPORTS_MAPPING_FILE), reports)
+
+	if runReport != nil {
+		runReport.Set("reports", reports)
+	}
+
+	return reports, nil
+}
+
+func ReadReportMapping() ReportMapping {
+	path := workspace.Path("mapping", REPORTS_MAPPING_FILE+".json")
+
+	if _, err := os.Stat(path); err != nil {
+		return ReportMapping{}
+	}
+
+	return filemanager.ReadJSONFile[ReportMapping](path)
+}
+
+// Table IDs of the source tables keyed by their upper case alias
+func sourceTableAliases(sourceConfig api.Quickbase) map[string]string {
+	aliases := make(map[string]string)
+	path := workspace.Path("tables", sourceConfig.AppId+".json")
+
+	if _, err := os.Stat(path); err != nil {
+		return aliases
+	}
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](path) {
+		if table.Alias != "" {
+			aliases[strings.ToUpper(table.Alias)] = table.ID
+		}
+	}
+
+	return aliases
+}
+
+// Rewrites qid= references using the reports of the table they follow, tableId is the table of Dbid() and of references without a table before them
+func RewriteReportIds(text string, tableId string, reports ReportMapping, aliases map[string]string) string {
+	if !qidRegex.MatchString(text) {
+		return text
+	}
+
+	contexts := reportContextRegex.FindAllStringIndex(text, -1)
+	known := make(map[string]bool)
+
+	for table := range reports {
+		known[table] = true
+	}
+
+	for _, table := range aliases {
+		known[table] = true
+	}
+
+	tableAt := func(index int) string {
+		table := tableId
+
+		for _, context := range contexts {
+			if context[0] >= index {
+				break
+			}
+
+			token := strings.Trim(text[context[0]:context[1]], "[]")
+
+			switch {
+			case strings.EqualFold(token, "Dbid()"):
+				table = tableId
+			case strings.HasPrefix(strings.ToUpper(token), "_DBID_"):
+				table = aliases[strings.ToUpper(token)]
+			case known[token] || dbidContextRegex.MatchString(text[:context[0]]):
+				// Words like bootstrap look like a DBID, only known tables and DBIDs in a URL set the table
+				table = token
+			}
+		}
+
+		return table
+	}
+
+	matches := qidRegex.FindAllStringSubmatchIndex(text, -1)
+
+	// Replacing from the end keeps the indexes of earlier matches valid
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i][0] > matches[j][0]
+	})
+
+	for _, match := range matches {
+		qid := text[match[2]:match[3]]
+
+		if target, ok := reports[tableAt(match[0])][qid]; ok {
+			text = text[:match[2]] + target + text[match[3]:]
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,74 @@
+package main
+
+import "testing"
+
+func TestRewriteReportIds(t *testing.T) {
+	reports := ReportMapping{
+		"bsrc00001": {"5": "15", "6": "16"},
+		"bsrc00002": {"5": "25"},
+	}
+	aliases := map[string]string{"_DBID_ORDERS": "bsrc00002"}
+
+	tests := []struct {
+		name    string
+		text    string
+		tableId string
+		want    string
+	}{
+		{
+			name:    "own table without a table before",
+			text:    `href="?a=q&qid=5"`,
+			tableId: "bsrc00001",
+			want:    `href="?a=q&qid=15"`,
+		},
+		{
+			name: "db URL sets the table",
+			text: `/db/bsrc00002?a=q&qid=5`,
+			want: `/db/bsrc00002?a=q&qid=25`,
+		},
+		{
+			name:    "alias sets the table",
+			text:    `URLRoot() & "db/" & [_DBID_ORDERS] & "?a=q&qid=5"`,
+			tableId: "bsrc00001",
+			want:    `URLRoot() & "db/" & [_DBID_ORDERS] & "?a=q&qid=25"`,
+		},
+		{
+			name:    "Dbid() goes back to the own table",
+			text:    `/db/bsrc00002?a=q&qid=5 Dbid() & "?a=q&qid=5"`,
+			tableId: "bsrc00001",
+			want:    `/db/bsrc00002?a=q&qid=25 Dbid() & "?a=q&qid=15"`,
+		},
+		{
+			name:    "words shaped like a DBID are not a table",
+			text:    `<link href="bootstrap.css"> <a href="?a=q&qid=6">`,
+			tableId: "bsrc00001",
+			want:    `<link href="bootstrap.css"> <a href="?a=q&qid=16">`,
+		},
+		{
+			name:    "unknown table in a URL is left alone",
+			text:    `/db/bother001?a=q&qid=5`,
+			tableId: "bsrc00001",
+			want:    `/db/bother001?a=q&qid=5`,
+		},
+		{
+			name:    "unpaired report is left alone",
+			text:    `?a=q&qid=7`,
+			tableId: "bsrc00001",
+			want:    `?a=q&qid=7`,
+		},
+		{
+			name:    "longer numbers are not a qid",
+			text:    `?a=q&qid=55`,
+			tableId: "bsrc00001",
+			want:    `?a=q&qid=55`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RewriteReportIds(test.text, test.tableId, reports, aliases); got != test.want {
+				t.Errorf("RewriteReportIds(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	APP_ACCESS    = "(app access)"
+	NO_PERMISSION = "-"
+	ROLE_MISSING  = "missing"
+)
+
+// PermissionDiff is a permission of a role which differs between the source and the target
+type PermissionDiff struct {
+	Role   string `json:"role"`
+	Table  string `json:"table"`
+	Field  string `json:"field,omitempty"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+var csvFlag = &cli.StringFlag{
+	Name:  "csv",
+	Usage: "Also write the permission matrix to this CSV file",
+}
+
+// Permissions keyed by role name, explicit permissions only
+func permissionsByRole(permissions []api.SchemaPermission, roleNames map[string]string) map[string]string {
+	byRole := make(map[string]string)
+
+	for _, permission := range permissions {
+		if name, ok := roleNames[permission.Role]; ok {
+			byRole[name] = permission.PermissionType
+		}
+	}
+
+	return byRole
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return NO_PERMISSION
+	}
+
+	return value
+}
+
+// Appends a diff for every role whose permission differs
+func comparePermissions(diffs []PermissionDiff, roles []string, table string, field string, source map[string]string, target map[string]string) []PermissionDiff {
+	for _, role := range roles {
+		if source[role] != target[role] {
+			diffs = append(diffs, PermissionDiff{Role: role, Table: table, Field: field, Source: orNone(source[role]), Target: orNone(target[role])})
+		}
+	}
+
+	return diffs
+}
+
+func fetchSchemas(config api.Quickbase, tableIds []string) (map[string]api.GetSchemaResponse, error) {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var fetchErr error
+
+	schemas := make(map[string]api.GetSchemaResponse)
+
+	for _, tableId := range tableIds {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			schema, err := config.GetSchema(runContext, tableId)
+
+			mutex.Lock()
+			schemas[tableId] = schema
+			fetchErr = errors.Join(fetchErr, err)
+			mutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return schemas, fetchErr
+}
+
+// Compares the app access and the table and field permissions of roles with the same name
+func CompareRoles(sourceConfig api.Quickbase, targetConfig api.Quickbase, mapping map[string]string) ([]string, []PermissionDiff, error) {
+	log.Println(boldLogStyle.Render("Comparing roles..."))
+
+	sourceInfo, err := sourceConfig.GetRoleInfo(runContext)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targetInfo, err := targetConfig.GetRoleInfo(runContext)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sourceRoles, targetRoles := sourceInfo.Roles, targetInfo.Roles
+
+	sourceNames := make(map[string]string)
+	targetNames := make(map[string]string)
+	sourceAccess := make(map[string]string)
+	targetAccess := make(map[string]string)
+	names := make(map[string]bool)
+
+	for _, role := range sourceRoles {
+		sourceNames[role.ID] = role.Name
+		sourceAccess[role.Name] = role.Access
+		names[role.Name] = true
+	}
+
+	for _, role := range targetRoles {
+		targetNames[role.ID] = role.Name
+		targetAccess[role.Name] = role.Access
+		names[role.Name] = true
+	}
+
+	roles := make([]string, 0)
+
+	for name := range names {
+		roles = append(roles, name)
+	}
+
+	sort.Strings(roles)
+
+	diffs := make([]PermissionDiff, 0)
+
+	for _, role := range roles {
+		_, inSource := sourceAccess[role]
+		_, inTarget := targetAccess[role]
+
+		if !inSource || !inTarget || sourceAccess[role] != targetAccess[role] {
+			source, target := orNone(sourceAccess[role]), orNone(targetAccess[role])
+
+			if !inSource {
+				source = ROLE_MISSING
+			}
+
+			if !inTarget {
+				target = ROLE_MISSING
+			}
+
+			diffs = append(diffs, PermissionDiff{Role: role, Table: APP_ACCESS, Source: source, Target: target})
+		}
+	}
+
+	tables := make(map[string]api.Table)
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](workspace.Path("tables", sourceConfig.AppId+".json")) {
+		tables[table.ID] = table
+	}
+
+	scopedTables := filemanager.ReadJSONFile[[]string](workspace.Path("mapping", SCOPE_FILE+".json"))
+	targetIds := make([]string, 0)
+
+	for _, tableId := range scopedTables {
+		targetIds = append(targetIds, mapping[tableId])
+	}
+
+	sourceSchemas, err := fetchSchemas(sourceConfig, scopedTables)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targetSchemas, err := fetchSchemas(targetConfig, targetIds)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(scopedTables, func(i, j int) bool {
+		return tables[scopedTables[i]].Name < tables[scopedTables[j]].Name
+	})
+
+	for _, tableId := range scopedTables {
+		source := sourceSchemas[tableId].Table
+		target := targetSchemas[mapping[tableId]].Table
+		tableName := tables[tableId].Name
+
+		diffs = comparePermissions(diffs, roles, tableName, "", permissionsByRole(source.Permissions, sourceNames), permissionsByRole(target.Permissions, targetNames))
+
+		// Fields are mapped by label like the rest of the migration
+		targetFields := make(map[string]api.SchemaField)
+
+		for _, field := range target.Fields {
+			targetFields[field.Label] = field
+		}
+
+		for _, field := range source.Fields {
+			targetField, ok := targetFields[field.Label]
+			fieldId, _ := strconv.Atoi(field.ID)
+
+			if !ok || !runScope.IncludesField(api.Field{ID: fieldId, Label: field.Label}) {
+				continue
+			}
+
+			diffs = comparePermissions(diffs, roles, tableName, field.Label, permissionsByRole(field.Permissions, sourceNames), permissionsByRole(targetField.Permissions, targetNames))
+		}
+	}
+
+	return roles, diffs, nil
+}
+
+// Rows of the matrix are tables and fields, columns are roles, cells show source -> target where they differ
+func permissionMatrix(roles []string, diffs []PermissionDiff) [][]string {
+	header := append([]string{"Table", "Field"}, roles...)
+	matrix := [][]string{header}
+
+	rowIndex := make(map[string]int)
+	column := make(map[string]int)
+
+	for index, role := range roles {
+		column[role] = index + 2
+	}
+
+	for _, diff := range diffs {
+		key := diff.Table + "\x00" + diff.Field
+		index, ok := rowIndex[key]
+
+		if !ok {
+			row := make([]string, len(header))
+			row[0], row[1] = diff.Table, diff.Field
+			matrix = append(matrix, row)
+			index = len(matrix) - 1
+			rowIndex[key] = index
+		}
+
+		matrix[index][column[diff.Role]] = diff.Source + " -> " + diff.Target
+	}
+
+	return matrix
+}
+
+func PrintPermissionMatrix(roles []string, diffs []PermissionDiff) {
+	if len(diffs) == 0 {
+		log.Println(boldLogStyle.Render("Roles and permissions match"))
+		return
+	}
+
+	matrix := permissionMatrix(roles, diffs)
+	widths := make([]int, len(matrix[0]))
+
+	for _, row := range matrix {
+		for index, cell := range row {
+			widths[index] = max(widths[index], len([]rune(cell)))
+		}
+	}
+
+	for rowIndex, row := range matrix {
+		cells := make([]string, len(row))
+
+		for index, cell := range row {
+			cells[index] = fmt.Sprintf("%-*s", widths[index], cell)
+		}
+
+		line := strings.Join(cells, "  ")
+
+		if rowIndex == 0 {
+			fmt.Println(boldLogStyle.Render(line))
+		} else {
+			fmt.Println(warningStyle.Render(line))
+		}
+	}
+
+	log.Println(boldLogStyle.Render(fmt.Sprintf("%d permission differences in %d rows", len(diffs), len(matrix)-1)))
+}
+
+func SavePermissionMatrixCSV(path string, roles []string, diffs []PermissionDiff) {
+	file, err := os.Create(path)
+
+	if err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	if err := writer.WriteAll(permissionMatrix(roles, diffs)); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	log.Println(logStyle.Render("Saved permission matrix to " + path))
+}
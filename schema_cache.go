@@ -0,0 +1,228 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const SCHEMA_CACHE_FOLDER = "cache/schema"
+
+type SchemaCacheOptions struct {
+	Refresh bool
+	Offline bool
+	TTL     time.Duration
+}
+
+type SchemaCache struct {
+	AppId   string
+	Realm   string
+	Fetched time.Time
+	Tables  []api.Table
+	Fields  []TargetField
+}
+
+var schemaFlags = []cli.Flag{
+	&cli.BoolFlag{Name: "refresh", Usage: "Ignore the cached target schema and fetch it again"},
+	&cli.BoolFlag{Name: "offline", Usage: "Use the cached target schema without contacting Quickbase"},
+	&cli.DurationFlag{Name: "cache-ttl", Value: time.Hour, Usage: "Maximum age of the cached target schema before it is fetched again"},
+}
+
+func GetSchemaCacheOptions(ctx *cli.Context) SchemaCacheOptions {
+	options := SchemaCacheOptions{
+		Refresh: ctx.Bool("refresh"),
+		Offline: ctx.Bool("offline"),
+		TTL:     ctx.Duration("cache-ttl"),
+	}
+
+	if options.Refresh && options.Offline {
+		log.Fatal(errorStyle.Render("--refresh and --offline cannot be used together"))
+	}
+
+	return options
+}
+
+// The cache is written and read at this exact path, the realm and app ID are sanitized into a single file name
+func schemaCachePath(config api.Quickbase) string {
+	return workspace.BasePath(SCHEMA_CACHE_FOLDER, filemanager.SanitizeFileName(config.Realm+"_"+config.AppId)+".json")
+}
+
+func readSchemaCache(config api.Quickbase) (SchemaCache, bool) {
+	cachePath := schemaCachePath(config)
+
+	if _, err := os.Stat(cachePath); err != nil {
+		return SchemaCache{}, false
+	}
+
+	cache := filemanager.ReadJSONFile[SchemaCache](cachePath)
+
+	if cache.AppId != config.AppId || cache.Realm != config.Realm {
+		return SchemaCache{}, false
+	}
+
+	cache.Fields = mapFormulas(cache.Fields, func(formula string, source string) string {
+		return ResolveSecrets(formula, config, source)
+	})
+
+	return cache, true
+}
+
+// Copy of the fields of a schema with each formula replaced, used to keep secrets out of the cache file
+func mapFormulas(schema []TargetField, replace func(formula string, source string) string) []TargetField {
+	mapped := make([]TargetField, len(schema))
+
+	for index, target := range schema {
+		mapped[index] = target
+		mapped[index].Fields = make([]api.Field, len(target.Fields))
+
+		for fieldIndex, field := range target.Fields {
+			if field.Properties.Formula != "" {
+				field.Properties.Formula = replace(field.Properties.Formula, "Field "+field.Label)
+			}
+
+			mapped[index].Fields[fieldIndex] = field
+		}
+	}
+
+	return mapped
+}
+
+func writeSchemaCache(cache SchemaCache) {
+	if err := os.MkdirAll(workspace.BasePath(SCHEMA_CACHE_FOLDER), 0755); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	config := api.Quickbase{AppId: cache.AppId, Realm: cache.Realm}
+
+	cache.Fields = mapFormulas(cache.Fields, func(formula string, source string) string {
+		return ProtectSecrets(formula, config, source)
+	})
+
+	content, err := json.MarshalIndent(cache, "", "  ")
+
+	if err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	if err := os.WriteFile(schemaCachePath(config), content, 0644); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+}
+
+// Removes the cached schema of an app, used after writes to its fields
+func InvalidateSchemaCache(config api.Quickbase) {
+	if err := os.Remove(schemaCachePath(config)); err != nil && !os.IsNotExist(err) {
+		log.Println(warningStyle.Render(err.Error()))
+	}
+}
+
+// Fetches the fields of the given tables, reusing the cached fields of tables which have not been updated
+func fetchTableFields(config api.Quickbase, tables []api.Table, cached map[string]TargetField) ([]TargetField, error) {
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	var fetchErr error
+
+	targetFields := make([]TargetField, len(tables))
+
+	for index, table := range tables {
+		if target, ok := cached[table.ID]; ok {
+			targetFields[index] = target
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(i int, t api.Table) {
+			defer wg.Done()
+
+			fields, err := config.GetFields(runContext, t.ID)
+
+			mutex.Lock()
+			fetchErr = errors.Join(fetchErr, err)
+			mutex.Unlock()
+
+			targetFields[i] = TargetField{
+				TableId:    t.ID,
+				TableName:  filemanager.SanitizeFileName(t.Name),
+				TableAlias: t.Alias,
+				Fields:     fields,
+			}
+		}(index, table)
+	}
+
+	wg.Wait()
+
+	return targetFields, fetchErr
+}
+
+func LoadSchema(config api.Quickbase, options SchemaCacheOptions) ([]TargetField, error) {
+	cache, found := readSchemaCache(config)
+
+	if options.Offline {
+		if !found {
+			return nil, errors.New("no cached schema for app " + config.AppId + " on " + config.Realm)
+		}
+
+		log.Println(warningStyle.Render("Using cached schema from " + cache.Fetched.Format(time.RFC3339)))
+
+		return cache.Fields, nil
+	}
+
+	res, err := config.GetTables(runContext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tables := res.Tables
+	cached := make(map[string]TargetField)
+	fetched := time.Now()
+
+	if found && !options.Refresh && time.Since(cache.Fetched) < options.TTL {
+		updated := make(map[string]time.Time)
+
+		for _, table := range cache.Tables {
+			updated[table.ID] = table.Updated
+		}
+
+		for _, target := range cache.Fields {
+			cached[target.TableId] = target
+		}
+
+		for _, table := range tables {
+			if lastUpdated, ok := updated[table.ID]; !ok || !lastUpdated.Equal(table.Updated) {
+				log.Println(warningStyle.Render("Table " + table.Name + " changed since last fetch"))
+				delete(cached, table.ID)
+			}
+		}
+
+		if len(cached) > 0 {
+			log.Println(logStyle.Render("Using cached schema for unchanged tables"))
+			fetched = cache.Fetched
+		}
+	}
+
+	targetFields, err := fetchTableFields(config, tables, cached)
+
+	// A partial schema is never cached
+	if err != nil {
+		return nil, err
+	}
+
+	writeSchemaCache(SchemaCache{
+		AppId:   config.AppId,
+		Realm:   config.Realm,
+		Fetched: fetched,
+		Tables:  tables,
+		Fields:  targetFields,
+	})
+
+	return targetFields, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"app-configuration/api"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+const SCOPE_FILE = "scope"
+
+// Scope restricts a run to the given tables, pages and fields, each given as a name, alias or ID
+type Scope struct {
+	Tables        []string `json:"tables,omitempty"`
+	ExcludeTables []string `json:"excludeTables,omitempty"`
+	Pages         []string `json:"pages,omitempty"`
+	Fields        []string `json:"fields,omitempty"`
+}
+
+var runScope Scope
+
+var scopeFlags = []cli.Flag{
+	&cli.StringSliceFlag{Name: "table", Usage: "Only process these tables (name, alias or ID, repeatable)"},
+	&cli.StringSliceFlag{Name: "exclude-table", Usage: "Skip these tables (name, alias or ID, repeatable)"},
+	&cli.StringSliceFlag{Name: "page", Usage: "Only process these code pages (name or ID, repeatable)"},
+	&cli.StringSliceFlag{Name: "field", Usage: "Only process these fields (label or ID, repeatable)"},
+}
+
+func GetScope(ctx *cli.Context) Scope {
+	return Scope{
+		Tables:        ctx.StringSlice("table"),
+		ExcludeTables: ctx.StringSlice("exclude-table"),
+		Pages:         ctx.StringSlice("page"),
+		Fields:        ctx.StringSlice("field"),
+	}
+}
+
+func normalizeScopeValue(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	return strings.TrimPrefix(value, "_dbid_")
+}
+
+func matchesAny(filters []string, candidates ...string) bool {
+	for _, filter := range filters {
+		for _, candidate := range candidates {
+			if candidate != "" && normalizeScopeValue(filter) == normalizeScopeValue(candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (s Scope) IsEmpty() bool {
+	return len(s.Tables) == 0 && len(s.ExcludeTables) == 0 && len(s.Pages) == 0 && len(s.Fields) == 0
+}
+
+func (s Scope) IncludesTable(id string, name string, alias string) bool {
+	if len(s.Tables) > 0 && !matchesAny(s.Tables, id, name, alias) {
+		return false
+	}
+
+	return !matchesAny(s.ExcludeTables, id, name, alias)
+}
+
+func (s Scope) IncludesPage(pageId string, name string) bool {
+	return len(s.Pages) == 0 || matchesAny(s.Pages, pageId, name)
+}
+
+func (s Scope) IncludesField(field api.Field) bool {
+	return len(s.Fields) == 0 || matchesAny(s.Fields, strconv.Itoa(field.ID), field.Label)
+}
+
+// Filters the tables and fields of a schema down to the scope
+func (s Scope) FilterSchema(schema []TargetField) []TargetField {
+	filtered := make([]TargetField, 0)
+
+	for _, target := range schema {
+		if !s.IncludesTable(target.TableId, target.TableName, target.TableAlias) {
+			continue
+		}
+
+		fields := make([]api.Field, 0)
+
+		for _, field := range target.Fields {
+			if s.IncludesField(field) {
+				fields = append(fields, field)
+			}
+		}
+
+		target.Fields = fields
+		filtered = append(filtered, target)
+	}
+
+	return filtered
+}
+
+// Warns about table filters which did not match any of the given tables
+func (s Scope) WarnUnmatchedTables(tables []api.Table) {
+	for _, filter := range append(append([]string{}, s.Tables...), s.ExcludeTables...) {
+		found := false
+
+		for _, table := range tables {
+			if matchesAny([]string{filter}, table.ID, table.Name, table.Alias) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			log.Println(warningStyle.Render("Table filter " + filter + " did not match any table"))
+		}
+	}
+}
+
+// Warns about page filters which did not match any of the given pages, given as names by page ID
+func (s Scope) WarnUnmatchedPages(pages map[string]string) {
+	for _, filter := range s.Pages {
+		found := false
+
+		for id, name := range pages {
+			if matchesAny([]string{filter}, id, name) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			log.Println(warningStyle.Render("Page filter " + filter + " did not match any code page"))
+		}
+	}
+}
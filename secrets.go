@@ -0,0 +1,177 @@
+package main
+
+import (
+	"app-configuration/api"
+	"app-configuration/config"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	SECRET_USERTOKEN = "USERTOKEN"
+	SECRET_APPTOKEN  = "APPTOKEN"
+	SECRET_TICKET    = "TICKET"
+)
+
+var secretPlaceholderRegex = regexp.MustCompile(`__QB_SECRET_([A-Z0-9_]+?)__`)
+
+var (
+	secretsMutex sync.RWMutex
+
+	// Secret values of each environment keyed by app ID, then by secret name
+	environmentSecrets = make(map[string]map[string]string)
+)
+
+func SecretPlaceholder(name string) string {
+	return "__QB_SECRET_" + name + "__"
+}
+
+// Resolves a secret value from config, values starting with env: are read from the environment
+func resolveSecretValue(value string) string {
+	if name, ok := strings.CutPrefix(value, "env:"); ok {
+		return os.Getenv(name)
+	}
+
+	return value
+}
+
+// Registers the user token and named secrets of an environment so they can be replaced and redacted
+func RegisterEnvironmentSecrets(environment api.Quickbase, appConfig config.AppConfig) {
+	secrets := map[string]string{SECRET_USERTOKEN: environment.UserToken}
+
+	if environment.AppToken != "" {
+		secrets[SECRET_APPTOKEN] = environment.AppToken
+	}
+
+	if ticket := resolveSecretValue(appConfig.Ticket); ticket != "" {
+		secrets[SECRET_TICKET] = ticket
+	}
+
+	for name, value := range appConfig.Secrets {
+		secrets[strings.ToUpper(name)] = resolveSecretValue(value)
+	}
+
+	secretsMutex.Lock()
+	environmentSecrets[environment.AppId] = secrets
+	secretsMutex.Unlock()
+}
+
+type secretValue struct {
+	appId string
+	name  string
+	value string
+}
+
+// Known secrets of all environments, longest first so a secret is never partly replaced
+func knownSecrets() []secretValue {
+	secretsMutex.RLock()
+	defer secretsMutex.RUnlock()
+
+	values := make([]secretValue, 0)
+
+	for appId, secrets := range environmentSecrets {
+		for name, value := range secrets {
+			if len(value) > 0 {
+				values = append(values, secretValue{appId: appId, name: name, value: value})
+			}
+		}
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i].value) > len(values[j].value)
+	})
+
+	return values
+}
+
+// Replaces the secrets in a page or formula with placeholders, warning about secrets which belong to another environment
+func ProtectSecrets(text string, environment api.Quickbase, source string) string {
+	secretsMutex.RLock()
+	ownValues := make(map[string]bool)
+
+	for _, value := range environmentSecrets[environment.AppId] {
+		ownValues[value] = true
+	}
+
+	secretsMutex.RUnlock()
+
+	for _, secret := range knownSecrets() {
+		if !strings.Contains(text, secret.value) {
+			continue
+		}
+
+		if secret.appId != environment.AppId && !ownValues[secret.value] {
+			log.Println(warningStyle.Render(source + " contains the " + secret.name + " secret of app " + secret.appId + ", not of app " + environment.AppId))
+		}
+
+		text = strings.ReplaceAll(text, secret.value, SecretPlaceholder(secret.name))
+	}
+
+	for _, match := range tokenRegex.FindAllStringSubmatch(text, -1) {
+		if !strings.HasPrefix(match[2], "__QB_SECRET_") {
+			log.Println(warningStyle.Render(source + " contains an unknown " + strings.ToLower(match[1]) + " " + RedactSecret(match[2])))
+		}
+	}
+
+	for _, match := range tokenHeaderRegex.FindAllStringSubmatch(text, -1) {
+		if !strings.HasPrefix(match[1], "__QB_SECRET_") {
+			log.Println(warningStyle.Render(source + " contains an unknown user token " + RedactSecret(match[1])))
+		}
+	}
+
+	return text
+}
+
+// Replaces the secret placeholders of a page or formula with the values of the environment it is pushed to
+func ResolveSecrets(text string, environment api.Quickbase, source string) string {
+	secretsMutex.RLock()
+	secrets := environmentSecrets[environment.AppId]
+	secretsMutex.RUnlock()
+
+	return secretPlaceholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := secretPlaceholderRegex.FindStringSubmatch(placeholder)[1]
+
+		if value, ok := secrets[name]; ok && value != "" {
+			return value
+		}
+
+		log.Println(errorStyle.Render(source + " uses secret " + name + " which is not configured for app " + environment.AppId))
+
+		return placeholder
+	})
+}
+
+func HasSecretPlaceholders(text string) bool {
+	return secretPlaceholderRegex.MatchString(text)
+}
+
+// Replaces every known secret with its redacted form
+func Redact(text string) string {
+	for _, secret := range knownSecrets() {
+		text = strings.ReplaceAll(text, secret.value, RedactSecret(secret.value))
+	}
+
+	return text
+}
+
+type redactingWriter struct {
+	next io.Writer
+}
+
+func (w redactingWriter) Write(p []byte) (int, error) {
+	if _, err := w.next.Write([]byte(Redact(string(p)))); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Wraps a log writer so that known secrets are never written
+func RedactingWriter(next io.Writer) io.Writer {
+	return redactingWriter{next: next}
+}
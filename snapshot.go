@@ -0,0 +1,222 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+const SNAPSHOT_FOLDER = "snapshot"
+
+var snapshotNameRegex = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+// Keys which change without a configuration change and would only add noise to the history
+var (
+	volatileAppKeys   = []string{"updated"}
+	volatileTableKeys = []string{"updated", "spaceUsed", "spaceRemaining", "nextRecordId"}
+)
+
+var snapshotDirFlag = &cli.StringFlag{
+	Name:  "dir",
+	Value: SNAPSHOT_FOLDER,
+	Usage: "Directory the snapshot is written to, each app is saved in a folder named after its ID",
+}
+
+// Lowercase file name with every run of unsafe characters replaced by a dash
+func snapshotName(value string) string {
+	return strings.Trim(snapshotNameRegex.ReplaceAllString(strings.ToLower(value), "-"), "-")
+}
+
+// Converts a value to a map so its volatile keys can be removed, map keys are always encoded sorted
+func stripVolatile(value any, keys []string) map[string]any {
+	content, err := json.Marshal(value)
+
+	if err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	var result map[string]any
+
+	json.Unmarshal(content, &result)
+
+	for _, key := range keys {
+		delete(result, key)
+	}
+
+	return result
+}
+
+func saveSnapshotFile(path string, content any) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	if err := filemanager.SaveJsonToFile(path, content); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+}
+
+// Folder name of a table, its alias without the _DBID_ prefix or its ID when it has none
+func snapshotTableName(table api.Table) string {
+	if alias := snapshotName(strings.TrimPrefix(strings.ToUpper(table.Alias), "_DBID_")); alias != "" {
+		return alias
+	}
+
+	return table.ID
+}
+
+// Writes the app, its tables, fields and code pages as a sorted tree which only changes with the configuration
+func Snapshot(config api.Quickbase, dir string) error {
+	log.Println(boldLogStyle.Render("Saving snapshot of app " + config.AppId + "..."))
+
+	appDir := filepath.Join(dir, config.AppId)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	// The snapshot is built next to the previous one, which is only replaced once every call has succeeded
+	buildDir, err := os.MkdirTemp(dir, "."+config.AppId+"-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(buildDir)
+
+	if err := os.Chmod(buildDir, 0755); err != nil {
+		return err
+	}
+
+	app, err := config.GetApp(runContext)
+
+	if err != nil {
+		return err
+	}
+
+	saveSnapshotFile(filepath.Join(buildDir, "app"), stripVolatile(app, volatileAppKeys))
+
+	res, err := config.GetTables(runContext)
+
+	if err != nil {
+		return err
+	}
+
+	tables := res.Tables
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].ID < tables[j].ID
+	})
+
+	used := make(map[string]bool)
+	fieldCount := 0
+
+	tableFields, err := fetchTableFields(config, tables, nil)
+
+	if err != nil {
+		return err
+	}
+
+	for index, target := range tableFields {
+		table := tables[index]
+		tableName := snapshotTableName(table)
+
+		if used[tableName] {
+			tableName += "-" + table.ID
+		}
+
+		used[tableName] = true
+		tableDir := filepath.Join(buildDir, "tables", tableName)
+
+		saveSnapshotFile(filepath.Join(tableDir, "table"), stripVolatile(table, volatileTableKeys))
+
+		for _, field := range target.Fields {
+			fileName := strconv.Itoa(field.ID)
+
+			if label := snapshotName(field.Label); label != "" {
+				fileName += "-" + label
+			}
+
+			// Formulas calling the API embed tokens just like pages
+			field.Properties.Formula = ProtectSecrets(field.Properties.Formula, config, "Field "+field.Label)
+
+			saveSnapshotFile(filepath.Join(tableDir, "fields", fileName), field)
+			fieldCount += 1
+		}
+
+		log.Println(logStyle.Render("Saved Table -- " + table.Name))
+	}
+
+	schema, err := config.GetSchema(runContext, config.AppId)
+
+	if err != nil {
+		return err
+	}
+
+	pages := schema.Table.Pages
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].ID < pages[j].ID
+	})
+
+	pagesMetadata := make([]map[string]string, 0)
+
+	if len(pages) > 0 {
+		if err := os.MkdirAll(filepath.Join(buildDir, PAGES_FOLDER), 0755); err != nil {
+			return err
+		}
+	}
+
+	usedPages := make(map[string]bool)
+
+	for _, page := range pages {
+		fileName := pageFileName(page)
+
+		if usedPages[fileName] {
+			fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "-" + page.ID + filepath.Ext(fileName)
+		}
+
+		usedPages[fileName] = true
+
+		res, err := config.GetPage(runContext, page.ID)
+
+		if err != nil {
+			return err
+		}
+
+		// Secrets never end up in a snapshot as it is meant to be committed
+		content := ProtectSecrets(res.PageBody, config, "Code Page "+page.Name())
+
+		filemanager.SaveFile(filepath.Join(buildDir, PAGES_FOLDER, fileName), content)
+		pagesMetadata = append(pagesMetadata, map[string]string{"id": page.ID, "name": page.Name(), "type": page.Type, "file": fileName})
+	}
+
+	saveSnapshotFile(filepath.Join(buildDir, PAGES_METADATA_FILE), pagesMetadata)
+
+	if err := runContext.Err(); err != nil {
+		return err
+	}
+
+	// Removed tables, fields and pages must disappear from the snapshot as well
+	if err := os.RemoveAll(appDir); err != nil {
+		return err
+	}
+
+	if err := os.Rename(buildDir, appDir); err != nil {
+		return err
+	}
+
+	log.Println(boldLogStyle.Render(fmt.Sprintf("Saved %d tables, %d fields and %d code pages to %s", len(tables), fieldCount, len(pages), appDir)))
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"app-configuration/api"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type failingTransport struct{}
+
+func (failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: 401, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"message":"Access denied"}`))}, nil
+}
+
+func TestSnapshotKeepsPreviousSnapshotOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	previous := filepath.Join(dir, "bsrc00000", "app.json")
+
+	if err := os.MkdirAll(filepath.Dir(previous), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(previous, []byte(`{"name":"Orders"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config := api.Quickbase{AppId: "bsrc00000", Realm: "source.quickbase.com", Auth: api.UserTokenAuth{UserToken: "b1_expired"}, Client: &http.Client{Transport: failingTransport{}}}
+
+	if err := Snapshot(config, dir); err == nil {
+		t.Fatal("Snapshot() succeeded with an expired token")
+	}
+
+	if content, err := os.ReadFile(previous); err != nil || string(content) != `{"name":"Orders"}` {
+		t.Errorf("previous snapshot was changed: %q, %v", content, err)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("snapshot folder holds %d entries, want only the previous snapshot", len(entries))
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"app-configuration/api"
+	filemanager "app-configuration/file_manager"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+)
+
+// Properties which are copied when a field is created, the others are set by Quickbase or belong to formulas
+var createFieldProperties = []string{"maxLength", "numLines", "appendOnly", "allowHTML", "allowMentions", "sortAsGiven", "carryChoices", "allowNewChoices", "defaultValue", "choices"}
+
+// Source tables which have no table of the same name in the target, tables unpaired in the mapping editor are left out
+func MissingTables(sourceConfig api.Quickbase, targetConfig api.Quickbase, mapping map[string]string) []api.Table {
+	missing := make([]api.Table, 0)
+	overrides := ReadMappingOverrides(sourceConfig.AppId, targetConfig.AppId)
+
+	for _, table := range filemanager.ReadJSONFile[[]api.Table](workspace.Path("tables", sourceConfig.AppId+".json")) {
+		if _, ok := mapping[table.ID]; ok || !runScope.IncludesTable(table.ID, table.Name, table.Alias) {
+			continue
+		}
+
+		if targetId, ok := overrides[table.ID]; ok && targetId == "" {
+			continue
+		}
+
+		missing = append(missing, table)
+	}
+
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Name < missing[j].Name
+	})
+
+	return missing
+}
+
+// Fields which can be created before any formula, composite fields first as they create their own sub-fields
+func fieldsInCreateOrder(fields []api.Field) []api.Field {
+	subFields := make(map[int]bool)
+
+	for _, field := range fields {
+		for _, id := range field.Properties.CompositeFields {
+			subFields[id] = true
+		}
+	}
+
+	ordered := make([]api.Field, 0)
+
+	for _, field := range fields {
+		if field.ID > 5 && field.Mode == "" && !subFields[field.ID] {
+			ordered = append(ordered, field)
+		}
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		iComposite := len(ordered[i].Properties.CompositeFields) > 0
+		jComposite := len(ordered[j].Properties.CompositeFields) > 0
+
+		if iComposite != jComposite {
+			return iComposite
+		}
+
+		return ordered[i].ID < ordered[j].ID
+	})
+
+	return ordered
+}
+
+func createFieldBody(field api.Field) api.CreateFieldBody {
+	properties := stripVolatile(field.Properties, nil)
+	copied := make(map[string]any)
+
+	for _, key := range createFieldProperties {
+		if value, ok := properties[key]; ok {
+			copied[key] = value
+		}
+	}
+
+	return api.CreateFieldBody{
+		Label:            field.Label,
+		FieldType:        field.FieldType,
+		FieldHelp:        field.FieldHelp,
+		NoWrap:           field.NoWrap,
+		Bold:             field.Bold,
+		AppearsByDefault: field.AppearsByDefault,
+		FindEnabled:      field.FindEnabled,
+		Audited:          field.Audited,
+		Properties:       copied,
+	}
+}
+
+// Creates a missing table in the target with its non-formula fields, returning the ID of the new table
+func CreateTable(sourceConfig api.Quickbase, targetConfig api.Quickbase, table api.Table) (string, error) {
+	log.Println(logStyle.Render("Creating Table -- " + table.Name))
+
+	res, err := targetConfig.CreateTable(runContext, api.CreateTableBody{
+		Name:             table.Name,
+		Description:      table.Description,
+		SingleRecordName: table.SingleRecordName,
+		PluralRecordName: table.PluralRecordName,
+	})
+
+	Journal(targetConfig, JournalEntry{
+		Action:     "CreateTable",
+		TableId:    res.ID,
+		AfterHash:  HashContent(table.Name),
+		ResultCode: ResultCode(err),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if table.KeyFieldID != RECORD_ID_FIELD && table.KeyFieldID != 0 {
+		log.Println(warningStyle.Render("Table " + table.Name + " is keyed by field " + strconv.Itoa(table.KeyFieldID) + " in the source, set the key field of the new table manually"))
+	}
+
+	failed := 0
+
+	fields, err := sourceConfig.GetFields(runContext, table.ID)
+
+	if err != nil {
+		return res.ID, err
+	}
+
+	for _, field := range fieldsInCreateOrder(fields) {
+		if runContext.Err() != nil {
+			break
+		}
+
+		body := createFieldBody(field)
+		created, fieldErr := targetConfig.CreateField(runContext, res.ID, body)
+
+		if fieldErr != nil {
+			failed += 1
+
+			log.Println(errorStyle.Render("Failed to create Field -- " + table.Name + " / " + field.Label + " -- " + fieldErr.Error()))
+		}
+
+		Journal(targetConfig, JournalEntry{
+			Action:     "CreateField",
+			TableId:    res.ID,
+			FieldId:    strconv.Itoa(created.ID),
+			AfterHash:  HashContent(field.Label + "\x00" + field.FieldType),
+			ResultCode: ResultCode(fieldErr),
+		})
+	}
+
+	if failed > 0 {
+		return res.ID, fmt.Errorf("%d fields could not be created", failed)
+	}
+
+	return res.ID, nil
+}
+
+// Creates the source tables which are missing in the target, returning the tables which were created
+func CreateMissingTables(sourceConfig api.Quickbase, targetConfig api.Quickbase, missing []api.Table) []api.Table {
+	created := make([]api.Table, 0)
+
+	runProgress.Start(PHASE_TABLES, len(missing))
+
+	for _, table := range missing {
+		if runContext.Err() != nil {
+			break
+		}
+
+		runProgress.Begin(PHASE_TABLES, table.Name)
+
+		tableId, err := CreateTable(sourceConfig, targetConfig, table)
+
+		if tableId != "" {
+			created = append(created, table)
+		}
+
+		runProgress.Done(PHASE_TABLES, table.Name, err)
+	}
+
+	return created
+}
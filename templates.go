@@ -0,0 +1,249 @@
+package main
+
+import (
+	"app-configuration/api"
+	"app-configuration/config"
+	filemanager "app-configuration/file_manager"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Shorter variable values like 1, true or main are too common in scripts to be turned back into placeholders
+const MIN_TEMPLATE_VALUE_LENGTH = 6
+
+// Only the known placeholders are rendered so other {{ }} in page scripts are left untouched
+var templateRegex = regexp.MustCompile(`\{\{\s*\.(?:Tables\.([A-Za-z0-9_]+)|App\.(Id)|(Realm)|Env\s+"([^"]+)")\s*\}\}`)
+
+var (
+	templatesMutex sync.Mutex
+
+	// Variables of each environment keyed by app ID, then by variable name
+	environmentVariables = make(map[string]map[string]string)
+
+	// Tables of each environment keyed by app ID
+	environmentTables = make(map[string][]api.Table)
+)
+
+// Registers the template variables of an environment, values starting with env: are read from the environment
+func RegisterEnvironmentVariables(environment api.Quickbase, appConfig config.AppConfig) {
+	variables := make(map[string]string)
+
+	for name, value := range appConfig.Variables {
+		variables[name] = resolveSecretValue(value)
+	}
+
+	templatesMutex.Lock()
+	environmentVariables[environment.AppId] = variables
+	templatesMutex.Unlock()
+}
+
+// Tables of an environment, read from the tables saved by the mapping when available
+func templateTables(environment api.Quickbase) []api.Table {
+	templatesMutex.Lock()
+	defer templatesMutex.Unlock()
+
+	if tables, ok := environmentTables[environment.AppId]; ok {
+		return tables
+	}
+
+	var tables []api.Table
+	path := workspace.Path("tables", environment.AppId+".json")
+
+	if _, err := os.Stat(path); err == nil {
+		tables = filemanager.ReadJSONFile[[]api.Table](path)
+	} else {
+		res, err := environment.GetTables(runContext)
+
+		// Table placeholders are left unrendered, the tables are fetched again by the next page
+		if err != nil {
+			log.Println(errorStyle.Render("Failed to list the tables of app " + environment.AppId + " -- " + err.Error()))
+			return nil
+		}
+
+		tables = res.Tables
+	}
+
+	environmentTables[environment.AppId] = tables
+
+	return tables
+}
+
+// Placeholder names of the tables of an app keyed by table ID, assigned in name order so apps with the same tables agree
+func TemplateTableNames(tables []api.Table) map[string]string {
+	sorted := slices.Clone(tables)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	labels := make([]string, len(sorted))
+
+	for index, table := range sorted {
+		labels[index] = table.Name
+	}
+
+	names := make(map[string]string)
+
+	for index, name := range uniqueVariableNames(labels) {
+		names[sorted[index].ID] = name
+	}
+
+	return names
+}
+
+// Table of a placeholder name, matched against the table names first and then the aliases without the _DBID_ prefix
+func findTemplateTable(tables []api.Table, name string) (api.Table, bool) {
+	names := TemplateTableNames(tables)
+
+	for _, table := range tables {
+		if strings.EqualFold(names[table.ID], name) {
+			return table, true
+		}
+	}
+
+	for _, table := range tables {
+		alias := strings.TrimPrefix(strings.ToUpper(table.Alias), "_DBID_")
+
+		if alias != "" && strings.EqualFold(VariableName(alias), name) {
+			return table, true
+		}
+	}
+
+	return api.Table{}, false
+}
+
+func HasTemplatePlaceholders(text string) bool {
+	return templateRegex.MatchString(text)
+}
+
+// Renders the placeholders of a page for the environment it is pushed to
+func RenderTemplate(text string, environment api.Quickbase, source string) string {
+	if !HasTemplatePlaceholders(text) {
+		return text
+	}
+
+	templatesMutex.Lock()
+	variables := environmentVariables[environment.AppId]
+	templatesMutex.Unlock()
+
+	return templateRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		match := templateRegex.FindStringSubmatch(placeholder)
+
+		switch {
+		case match[1] != "":
+			if table, ok := findTemplateTable(templateTables(environment), match[1]); ok {
+				return table.ID
+			}
+
+			log.Println(errorStyle.Render(source + " uses table " + match[1] + " which does not exist in app " + environment.AppId))
+		case match[2] != "":
+			return environment.AppId
+		case match[3] != "":
+			return environment.Realm
+		default:
+			if value, ok := variables[match[4]]; ok {
+				return value
+			}
+
+			log.Println(errorStyle.Render(source + " uses variable " + match[4] + " which is not configured for app " + environment.AppId))
+		}
+
+		return placeholder
+	})
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// Replaces the occurrences of a value which are not part of a longer word or number
+func replaceToken(text string, value string, replacement string) string {
+	var builder strings.Builder
+
+	start := 0
+
+	for {
+		index := strings.Index(text[start:], value)
+
+		if index < 0 {
+			break
+		}
+
+		index += start
+		end := index + len(value)
+		before := index == 0 || !isWordByte(text[index-1]) || !isWordByte(value[0])
+		after := end == len(text) || !isWordByte(text[end]) || !isWordByte(value[len(value)-1])
+
+		builder.WriteString(text[start:index])
+
+		if before && after {
+			builder.WriteString(replacement)
+		} else {
+			builder.WriteString(value)
+		}
+
+		start = end
+	}
+
+	builder.WriteString(text[start:])
+
+	return builder.String()
+}
+
+// Turns the known IDs of an environment in a page back into placeholders
+func TemplatePage(text string, environment api.Quickbase) string {
+	tables := make(map[string]string)
+
+	for id, name := range TemplateTableNames(templateTables(environment)) {
+		tables[id] = "{{ .Tables." + name + " }}"
+	}
+
+	templatesMutex.Lock()
+	names := make([]string, 0)
+
+	for name, value := range environmentVariables[environment.AppId] {
+		if len(value) >= MIN_TEMPLATE_VALUE_LENGTH {
+			names = append(names, name)
+		}
+	}
+
+	variables := environmentVariables[environment.AppId]
+	templatesMutex.Unlock()
+
+	// Variables come first as their values may contain the realm, longest first so one is never partly replaced
+	sort.Slice(names, func(i, j int) bool {
+		return len(variables[names[i]]) > len(variables[names[j]])
+	})
+
+	for _, name := range names {
+		text = replaceToken(text, variables[name], "{{ .Env "+strconv.Quote(name)+" }}")
+	}
+
+	text = dbidRegex.ReplaceAllStringFunc(text, func(dbid string) string {
+		if dbid == environment.AppId {
+			return "{{ .App.Id }}"
+		}
+
+		if placeholder, ok := tables[dbid]; ok {
+			return placeholder
+		}
+
+		return dbid
+	})
+
+	if environment.Realm != "" {
+		text = strings.ReplaceAll(text, environment.Realm, "{{ .Realm }}")
+	}
+
+	return text
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"app-configuration/api"
+	"testing"
+)
+
+func TestTemplateTablesRoundTrip(t *testing.T) {
+	source := api.Quickbase{AppId: "bsrc00000", Realm: "source.quickbase.com"}
+	target := api.Quickbase{AppId: "btgt00000", Realm: "target.quickbase.com"}
+
+	environmentTables[source.AppId] = []api.Table{
+		{ID: "btab00002", Name: "Orders!"},
+		{ID: "btab00001", Name: "Orders"},
+		{ID: "btab00003", Name: "Order Lines", Alias: "_DBID_LINES"},
+	}
+	environmentTables[target.AppId] = []api.Table{
+		{ID: "btgt00001", Name: "Orders"},
+		{ID: "btgt00003", Name: "Order Lines", Alias: "_DBID_LINES"},
+		{ID: "btgt00002", Name: "Orders!"},
+	}
+
+	t.Cleanup(func() {
+		delete(environmentTables, source.AppId)
+		delete(environmentTables, target.AppId)
+	})
+
+	page := `open("/db/btab00001"); open("/db/btab00002"); open("/db/btab00003")`
+	pulled := TemplatePage(page, source)
+
+	if want := `open("/db/{{ .Tables.Orders }}"); open("/db/{{ .Tables.Orders_2 }}"); open("/db/{{ .Tables.OrderLines }}")`; pulled != want {
+		t.Fatalf("TemplatePage() = %q, want %q", pulled, want)
+	}
+
+	tests := []struct {
+		name        string
+		environment api.Quickbase
+		text        string
+		want        string
+	}{
+		{
+			name:        "push to the same app",
+			environment: source,
+			text:        pulled,
+			want:        page,
+		},
+		{
+			name:        "push to another app",
+			environment: target,
+			text:        pulled,
+			want:        `open("/db/btgt00001"); open("/db/btgt00002"); open("/db/btgt00003")`,
+		},
+		{
+			name:        "alias placeholder",
+			environment: target,
+			text:        `open("/db/{{ .Tables.LINES }}")`,
+			want:        `open("/db/btgt00003")`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RenderTemplate(test.text, test.environment, "page"); got != test.want {
+				t.Errorf("RenderTemplate(%q) = %q, want %q", test.text, got, test.want)
+			}
+		})
+	}
+}
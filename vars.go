@@ -0,0 +1,176 @@
+package main
+
+import (
+	"app-configuration/api"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	VAR_SAME    = "same"
+	VAR_CHANGED = "changed"
+	VAR_MISSING = "missing"
+	VAR_EXTRA   = "extra"
+)
+
+// VariableDiff compares an app variable (DBvar) of the source with the target
+type VariableDiff struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Mapped string `json:"mapped"`
+	Target string `json:"target"`
+	Status string `json:"status"`
+}
+
+var allowVarFlag = &cli.StringSliceFlag{
+	Name:  "var",
+	Usage: "Variable allowed to be pushed to the target (repeatable)",
+}
+
+func getVariables(config api.Quickbase) (map[string]string, error) {
+	variables := make(map[string]string)
+
+	schema, err := config.GetSchema(runContext, config.AppId)
+
+	if err != nil {
+		return variables, err
+	}
+
+	for _, variable := range schema.Table.Variables {
+		variables[variable.Name] = variable.Value
+	}
+
+	return variables, nil
+}
+
+// Compares the app variables of both environments, applying the mapping to the source values
+func DiffVariables(sourceConfig api.Quickbase, targetConfig api.Quickbase, mapping map[string]string) ([]VariableDiff, error) {
+	log.Println(boldLogStyle.Render("Comparing app variables..."))
+
+	sourceVariables, err := getVariables(sourceConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	targetVariables, err := getVariables(targetConfig)
+
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]VariableDiff, 0)
+
+	for name, value := range sourceVariables {
+		mapped := value
+
+		for source, target := range mapping {
+			mapped = strings.ReplaceAll(mapped, source, target)
+		}
+
+		diff := VariableDiff{Name: name, Source: value, Mapped: mapped, Status: VAR_MISSING}
+
+		if target, ok := targetVariables[name]; ok {
+			diff.Target = target
+			diff.Status = VAR_CHANGED
+
+			if target == mapped {
+				diff.Status = VAR_SAME
+			}
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	for name, value := range targetVariables {
+		if _, ok := sourceVariables[name]; !ok {
+			diffs = append(diffs, VariableDiff{Name: name, Target: value, Status: VAR_EXTRA})
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs, nil
+}
+
+func PrintVariableDiffs(diffs []VariableDiff) {
+	counts := make(map[string]int)
+
+	for _, diff := range diffs {
+		counts[diff.Status] += 1
+
+		line := fmt.Sprintf("%-8s %-24s %-40s %s", diff.Status, diff.Name, truncate(Redact(diff.Mapped), 40), truncate(Redact(diff.Target), 40))
+
+		switch diff.Status {
+		case VAR_CHANGED, VAR_MISSING:
+			line = warningStyle.Render(line)
+		case VAR_EXTRA:
+			line = errorStyle.Render(line)
+		default:
+			line = logStyle.Render(line)
+		}
+
+		fmt.Println(line)
+	}
+
+	log.Println(boldLogStyle.Render(fmt.Sprintf("%d same, %d changed, %d missing in target, %d only in target", counts[VAR_SAME], counts[VAR_CHANGED], counts[VAR_MISSING], counts[VAR_EXTRA])))
+}
+
+// Pushes the allowed variables which differ from the target, with the mapping applied to their values
+func PushVariables(targetConfig api.Quickbase, diffs []VariableDiff, allowed []string) error {
+	if len(allowed) == 0 {
+		return errors.New("no variables allowed, pass each variable to push with --var")
+	}
+
+	byName := make(map[string]VariableDiff)
+
+	for _, diff := range diffs {
+		byName[strings.ToLower(diff.Name)] = diff
+	}
+
+	runProgress.Start(PHASE_VARIABLES, len(allowed))
+
+	for _, name := range allowed {
+		if runContext.Err() != nil {
+			break
+		}
+
+		runProgress.Begin(PHASE_VARIABLES, name)
+
+		diff, ok := byName[strings.ToLower(name)]
+
+		if !ok || diff.Status == VAR_EXTRA {
+			log.Println(warningStyle.Render("Variable " + name + " does not exist in the source app, skipping"))
+			runProgress.Done(PHASE_VARIABLES, name, errors.New("variable does not exist in the source app"))
+			continue
+		}
+
+		if diff.Status == VAR_SAME {
+			runProgress.Done(PHASE_VARIABLES, name, nil)
+			continue
+		}
+
+		log.Println(logStyle.Render("Updating Variable -- " + diff.Name))
+
+		_, varErr := targetConfig.SetDBVar(runContext, diff.Name, diff.Mapped)
+
+		Journal(targetConfig, JournalEntry{
+			Action:     "SetDBVar",
+			Variable:   diff.Name,
+			BeforeHash: HashContent(diff.Target),
+			AfterHash:  HashContent(diff.Mapped),
+			ResultCode: ResultCode(varErr),
+		})
+
+		runProgress.Done(PHASE_VARIABLES, name, varErr)
+	}
+
+	return runContext.Err()
+}
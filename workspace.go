@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	RUNS_FOLDER      = "runs"
+	LATEST_RUN_FILE  = "latest"
+	RUN_TIME_FORMAT  = "20060102-150405"
+	RUN_LOG_FILENAME = "run.log"
+)
+
+var runFolders = []string{"pages/source", "pages/target", "fields/source", "fields/target", "tables", "mapping", "rules"}
+
+// Workspace holds the output of a single invocation inside runs/<timestamp>-<command>
+type Workspace struct {
+	Base    string
+	Dir     string
+	LogFile *os.File
+}
+
+type RunInfo struct {
+	Name    string
+	Command string
+	Started time.Time
+	Latest  bool
+}
+
+var workspace Workspace
+
+var workspaceFlag = &cli.StringFlag{
+	Name:  "workspace",
+	Value: ".",
+	Usage: "Base directory holding the runs, cache and placeholders folders",
+}
+
+func (w Workspace) Path(elem ...string) string {
+	return filepath.Join(append([]string{w.Dir}, elem...)...)
+}
+
+func (w Workspace) Name() string {
+	return filepath.Base(w.Dir)
+}
+
+func (w Workspace) BasePath(elem ...string) string {
+	return filepath.Join(append([]string{w.Base}, elem...)...)
+}
+
+func runsPath(base string, elem ...string) string {
+	return filepath.Join(append([]string{base, RUNS_FOLDER}, elem...)...)
+}
+
+// Creates a new run directory with all output folders and points runs/latest at it
+func NewWorkspace(base string, command string) Workspace {
+	name := time.Now().Format(RUN_TIME_FORMAT) + "-" + command
+	dir := runsPath(base, name)
+
+	for suffix := 2; ; suffix++ {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			break
+		}
+
+		dir = runsPath(base, fmt.Sprintf("%s-%d", name, suffix))
+	}
+
+	w := Workspace{Base: base, Dir: dir}
+
+	for _, folder := range runFolders {
+		if err := os.MkdirAll(w.Path(folder), 0755); err != nil {
+			log.Fatal(errorStyle.Render(err.Error()))
+		}
+	}
+
+	if err := os.WriteFile(runsPath(base, LATEST_RUN_FILE), []byte(filepath.Base(dir)+"\n"), 0644); err != nil {
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	return w
+}
+
+// Creates the workspace for the current command, sending logs to both stderr and the run log
+func StartWorkspace(ctx *cli.Context) error {
+	workspace = NewWorkspace(ctx.String("workspace"), strings.ReplaceAll(ctx.Command.FullName(), " ", "-"))
+
+	logFile, err := os.Create(workspace.Path(RUN_LOG_FILENAME))
+
+	if err != nil {
+		return err
+	}
+
+	workspace.LogFile = logFile
+
+	log.SetOutput(RedactingWriter(io.MultiWriter(os.Stderr, logFile)))
+	log.Println(boldLogStyle.Render("Workspace -- " + workspace.Dir))
+
+	return nil
+}
+
+func LatestRun(base string) string {
+	content, err := os.ReadFile(runsPath(base, LATEST_RUN_FILE))
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(content))
+}
+
+// Lists all runs in the base directory, newest first
+func ListRuns(base string) []RunInfo {
+	entries, err := os.ReadDir(runsPath(base))
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []RunInfo{}
+		}
+
+		log.Fatal(errorStyle.Render(err.Error()))
+	}
+
+	latest := LatestRun(base)
+	runs := make([]RunInfo, 0)
+
+	for _, entry := range entries {
+		if !entry.IsDir() || len(entry.Name()) <= len(RUN_TIME_FORMAT) {
+			continue
+		}
+
+		started, err := time.ParseInLocation(RUN_TIME_FORMAT, entry.Name()[:len(RUN_TIME_FORMAT)], time.Local)
+
+		if err != nil {
+			continue
+		}
+
+		runs = append(runs, RunInfo{
+			Name:    entry.Name(),
+			Command: entry.Name()[len(RUN_TIME_FORMAT)+1:],
+			Started: started,
+			Latest:  entry.Name() == latest,
+		})
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Name > runs[j].Name
+	})
+
+	return runs
+}
+
+// Removes runs beyond the newest keep runs or older than the given age, never removing the latest run
+func PruneRuns(base string, keep int, olderThan time.Duration) []RunInfo {
+	pruned := make([]RunInfo, 0)
+
+	for index, run := range ListRuns(base) {
+		if run.Latest {
+			continue
+		}
+
+		tooMany := keep > 0 && index >= keep
+		tooOld := olderThan > 0 && time.Since(run.Started) > olderThan
+
+		if !tooMany && !tooOld {
+			continue
+		}
+
+		if err := os.RemoveAll(runsPath(base, run.Name)); err != nil {
+			log.Println(errorStyle.Render(err.Error()))
+			continue
+		}
+
+		pruned = append(pruned, run)
+	}
+
+	return pruned
+}